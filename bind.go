@@ -0,0 +1,226 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidTarget indicates that a value cannot be decoded into, e.g. because it is not a pointer to a struct.
+var ErrInvalidTarget = errors.New("invalid target")
+
+// Bind decodes the service msg into the struct target points to, using the same rules as Env.LoadService
+// for plain pointers. Additionally, fields tagged with `xsenv:"index:N"` are set to element N of the
+// array named like the field, so positional elements can be mapped to named fields.
+// As json tags must be unique, several fields taking elements of the same array name it using the from option:
+//
+//	type Endpoints struct {
+//		Primary string `json:"primary" xsenv:"from:endpoints,index:0"`
+//		Replica string `json:"replica" xsenv:"from:endpoints,index:1"`
+//	}
+//
+// Absent arrays leave such fields unchanged. Nil embedded struct pointers holding such fields are allocated.
+// It returns a *PathError wrapping ErrIndexOutOfRange if an array is too short and ErrInvalidTarget
+// if target is not a non-nil pointer to a struct or an embedded struct pointer cannot be allocated.
+func Bind(msg *json.RawMessage, target any) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %T is not a pointer to a struct", ErrInvalidTarget, target)
+	}
+	data, err := decodedData(msg)
+	if err != nil {
+		return err
+	}
+
+	var indexed []structField
+	for _, f := range structFields(rv.Elem().Type()) {
+		if f.opts.has("index") {
+			indexed = append(indexed, f)
+		}
+	}
+	if len(indexed) == 0 {
+		return json.Unmarshal(data, target)
+	}
+
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	// arrays bound by index are not decoded into the fields of the same name
+	arrays := make(map[string]json.RawMessage)
+	for _, f := range indexed {
+		for key, value := range values {
+			if strings.EqualFold(key, arrayName(f)) {
+				arrays[arrayName(f)] = value
+			}
+			if strings.EqualFold(key, arrayName(f)) || strings.EqualFold(key, f.name) {
+				delete(values, key)
+			}
+		}
+	}
+	if data, err = json.Marshal(values); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return err
+	}
+
+	for _, f := range indexed {
+		i, err := strconv.Atoi(f.opts["index"])
+		if err != nil || i < 0 {
+			return fmt.Errorf("%w: field %s has invalid index %q", ErrInvalidTarget, f.name, f.opts["index"])
+		}
+		name := arrayName(f)
+		array, ok := arrays[name]
+		if !ok {
+			continue
+		}
+		var elements []json.RawMessage
+		if err := json.Unmarshal(array, &elements); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		segment := strconv.Itoa(i)
+		if i >= len(elements) {
+			return &PathError{
+				Path:    name + "." + segment,
+				Segment: segment,
+				Err:     fmt.Errorf("%w: length is %d", ErrIndexOutOfRange, len(elements)),
+			}
+		}
+		field, ok := allocFieldByIndex(rv.Elem(), f.index)
+		if !ok {
+			return fmt.Errorf("%w: field %s of nil embedded struct cannot be allocated", ErrInvalidTarget, f.name)
+		}
+		if err := json.Unmarshal(elements[i], field.Addr().Interface()); err != nil {
+			return fmt.Errorf("%s.%s: %w", name, segment, err)
+		}
+	}
+	return nil
+}
+
+// allocFieldByIndex is like reflect.Value.FieldByIndex, but allocates nil embedded struct pointers on the way
+// like encoding/json does. The second return value is false if such a pointer cannot be set, e.g. as it is unexported.
+func allocFieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// arrayName returns the name of the array an indexed field takes its element from.
+func arrayName(f structField) string {
+	if from := f.opts["from"]; from != "" {
+		return from
+	}
+	return f.name
+}
+
+// DecodeWithExtras decodes the service msg into the struct target points to like Bind, and stores all
+// values of the credentials not matching a field of target in extras, keyed as in the credentials.
+// This keeps fields added to a binding later accessible without changing the struct. Field names are matched
+// ignoring case, like encoding/json does. To capture the extra values as part of the struct, implement
+// UnmarshalService using a field ignored by encoding/json:
+//
+//	type UAA struct {
+//		ClientID string                     `json:"clientid"`
+//		Extra    map[string]json.RawMessage `json:"-"`
+//	}
+//
+//	func (u *UAA) UnmarshalService(msg *json.RawMessage) error {
+//		return xsenv.DecodeWithExtras(msg, u, &u.Extra)
+//	}
+//
+// extras is set to nil if there are no extra values.
+func DecodeWithExtras(msg *json.RawMessage, target any, extras *map[string]json.RawMessage) error {
+	if err := Bind(msg, target); err != nil {
+		return err
+	}
+	data, err := decodedData(msg)
+	if err != nil {
+		return err
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+
+	var known []string
+	for _, f := range structFields(reflect.TypeOf(target).Elem()) {
+		known = append(known, f.name, arrayName(f))
+	}
+	*extras = nil
+	for key, value := range values {
+		if slices.ContainsFunc(known, func(name string) bool { return strings.EqualFold(name, key) }) {
+			continue
+		}
+		if *extras == nil {
+			*extras = make(map[string]json.RawMessage)
+		}
+		(*extras)[key] = value
+	}
+	return nil
+}
+
+// BindAll populates the fields of the struct target points to from the services of e, so the whole configuration
+// of an application can be declared in a single struct. Fields tagged with `xsenv:"service:name"` are loaded
+// from the service with that name like Env.LoadService, and slice fields tagged with `xsenv:"group:name"`
+// receive all services of that group in their order like LoadGroup, e.g. several database instances:
+//
+//	type Config struct {
+//		UAA       UAAConfig  `xsenv:"service:uaa"`
+//		Databases []DBConfig `xsenv:"group:hana"`
+//	}
+//
+// An absent group results in an empty slice. Untagged fields are left unchanged.
+// It returns ErrServiceNotFound if a service does not exist and ErrInvalidTarget if target is not
+// a non-nil pointer to a struct or a group is bound to a field which is no slice.
+// Errors are prefixed with the name of the field.
+func (e *Env) BindAll(target any) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %T is not a pointer to a struct", ErrInvalidTarget, target)
+	}
+	for _, f := range structFields(rv.Elem().Type()) {
+		service, group := f.opts["service"], f.opts["group"]
+		if service == "" && group == "" {
+			continue
+		}
+		field, err := rv.Elem().FieldByIndexErr(f.index)
+		if err != nil {
+			return fmt.Errorf("%s: %w: field of nil embedded struct", f.name, ErrInvalidTarget)
+		}
+		if service != "" {
+			if err := e.LoadService(field.Addr().Interface(), service); err != nil {
+				return fmt.Errorf("%s: %w", f.name, err)
+			}
+			continue
+		}
+		if f.typ.Kind() != reflect.Slice {
+			return fmt.Errorf("%s: %w: group %s must be bound to a slice, not %s", f.name, ErrInvalidTarget, group, f.typ)
+		}
+		keys := e.groups[group]
+		values := reflect.MakeSlice(f.typ, 0, len(keys))
+		for _, key := range keys {
+			value := reflect.New(f.typ.Elem())
+			if err := e.decode(value.Interface(), key); err != nil {
+				return fmt.Errorf("%s: %s: %w", f.name, key, err)
+			}
+			values = reflect.Append(values, value.Elem())
+		}
+		field.Set(values)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testEndpoint struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+type testHAConfig struct {
+	User    string       `json:"user"`
+	Primary testEndpoint `json:"primary" xsenv:"from:endpoints,index:0"`
+	Replica testEndpoint `json:"replica" xsenv:"from:endpoints,index:1"`
+	First   string       `json:"hosts" xsenv:"index:0"`
+}
+
+func TestBind(t *testing.T) {
+	msg := json.RawMessage(`{"name": "db", "credentials": {
+		"user": "app",
+		"endpoints": [{"host": "primary", "port": 1}, {"host": "replica", "port": 2}],
+		"HOSTS": ["a", "b"]
+	}}`)
+	var config testHAConfig
+	assert.NoError(t, Bind(&msg, &config))
+	assert.Equal(t, testHAConfig{
+		User:    "app",
+		Primary: testEndpoint{Host: "primary", Port: 1},
+		Replica: testEndpoint{Host: "replica", Port: 2},
+		First:   "a",
+	}, config)
+
+	// absent arrays leave the fields unchanged
+	msg = json.RawMessage(`{"credentials": {"user": "other"}}`)
+	assert.NoError(t, Bind(&msg, &config))
+	assert.Equal(t, "other", config.User)
+	assert.Equal(t, "primary", config.Primary.Host)
+
+	// plain structs behave like LoadService
+	msg = json.RawMessage(`{"host": "plain.example.com", "port": "30015"}`)
+	var plain testHANAConfig
+	assert.NoError(t, Bind(&msg, &plain))
+	assert.Equal(t, testHANAConfig{Host: "plain.example.com", Port: "30015"}, plain)
+}
+
+type testInnerEndpoints struct {
+	Primary string `json:"primary" xsenv:"from:endpoints,index:0"`
+}
+
+func TestBindEmbeddedPointer(t *testing.T) {
+	type Inner testInnerEndpoints
+	msg := json.RawMessage(`{"credentials": {"host": "h", "endpoints": ["a", "b"]}}`)
+	var config struct {
+		*Inner
+		Host string `json:"host"`
+	}
+	assert.NoError(t, Bind(&msg, &config))
+	assert.Equal(t, "h", config.Host)
+	if assert.NotNil(t, config.Inner) {
+		assert.Equal(t, "a", config.Primary)
+	}
+
+	// unexported embedded pointers cannot be allocated
+	var unexported struct {
+		*testInnerEndpoints
+		Host string `json:"host"`
+	}
+	assert.ErrorIs(t, Bind(&msg, &unexported), ErrInvalidTarget)
+}
+
+func TestBindErrors(t *testing.T) {
+	msg := json.RawMessage(`{"credentials": {"endpoints": [{"host": "primary"}]}}`)
+	var config testHAConfig
+	err := Bind(&msg, &config)
+	assert.ErrorIs(t, err, ErrIndexOutOfRange)
+	assert.EqualError(t, err, "endpoints.1: at 1: index out of range: length is 1")
+
+	msg = json.RawMessage(`{"credentials": {"endpoints": {"host": "primary"}}}`)
+	assert.ErrorContains(t, Bind(&msg, &config), "endpoints: ")
+
+	msg = json.RawMessage(`{"credentials": {"hosts": [1]}}`)
+	assert.ErrorContains(t, Bind(&msg, &config), "hosts.0: ")
+
+	var invalid struct {
+		Host string `json:"hosts" xsenv:"index:first"`
+	}
+	assert.ErrorIs(t, Bind(&msg, &invalid), ErrInvalidTarget)
+	assert.ErrorIs(t, Bind(&msg, config), ErrInvalidTarget)
+	assert.ErrorIs(t, Bind(&msg, (*testHAConfig)(nil)), ErrInvalidTarget)
+}
+
+type testExtrasConfig struct {
+	ClientID string                     `json:"clientid"`
+	URL      string                     `json:"url"`
+	Extra    map[string]json.RawMessage `json:"-"`
+}
+
+func (c *testExtrasConfig) UnmarshalService(msg *json.RawMessage) error {
+	return DecodeWithExtras(msg, c, &c.Extra)
+}
+
+func TestDecodeWithExtras(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"xsuaa": [
+		{"name": "uaa", "credentials": {"clientid": "client", "URL": "https://uaa.example.com", "zoneid": "zone", "verify": {"tls": true}}},
+		{"name": "known", "credentials": {"clientid": "client"}}
+	]}}`), FileSource)
+	assert.NoError(t, err)
+
+	var config testExtrasConfig
+	assert.NoError(t, env.LoadService(&config, "uaa"))
+	assert.Equal(t, "client", config.ClientID)
+	assert.Equal(t, "https://uaa.example.com", config.URL)
+	assert.Equal(t, map[string]json.RawMessage{
+		"zoneid": json.RawMessage(`"zone"`),
+		"verify": json.RawMessage(`{"tls": true}`),
+	}, config.Extra)
+
+	assert.NoError(t, env.LoadService(&config, "known"))
+	assert.Nil(t, config.Extra)
+
+	// arrays bound by index are known fields
+	msg := json.RawMessage(`{"credentials": {"user": "app", "endpoints": [{}, {}], "hosts": ["a"], "region": "eu10"}}`)
+	var ha testHAConfig
+	var extras map[string]json.RawMessage
+	assert.NoError(t, DecodeWithExtras(&msg, &ha, &extras))
+	assert.Equal(t, map[string]json.RawMessage{"region": json.RawMessage(`"eu10"`)}, extras)
+
+	assert.ErrorIs(t, DecodeWithExtras(&msg, ha, &extras), ErrInvalidTarget)
+}
+
+func TestBindAll(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(testGroupEnv), FileSource)
+	assert.NoError(t, err)
+
+	var config struct {
+		Primary   testHANAConfig     `xsenv:"service:db-1"`
+		Databases []testHANAConfig   `xsenv:"group:hana"`
+		Pointers  []*testHANAConfig  `xsenv:"group:hana"`
+		Named     []testNamedService `xsenv:"group:hana"`
+		None      []testHANAConfig   `xsenv:"group:postgresql"`
+		Untouched string
+	}
+	config.Untouched = "kept"
+	assert.NoError(t, env.BindAll(&config))
+	assert.Equal(t, testHANAConfig{Host: "one.example.com", Port: "30015"}, config.Primary)
+	assert.Equal(t, []testHANAConfig{
+		{Host: "one.example.com", Port: "30015"},
+		{Host: "two.example.com", Port: "30041"},
+	}, config.Databases)
+	assert.Len(t, config.Pointers, 2)
+	assert.Equal(t, "two.example.com", config.Pointers[1].Host)
+	assert.Equal(t, []testNamedService{{Name: "db-1"}, {Name: "db-2"}}, config.Named)
+	assert.NotNil(t, config.None)
+	assert.Empty(t, config.None)
+	assert.Equal(t, "kept", config.Untouched)
+
+	var missing struct {
+		UAA testHANAConfig `xsenv:"service:uaa"`
+	}
+	err = env.BindAll(&missing)
+	assert.ErrorIs(t, err, ErrServiceNotFound)
+	assert.EqualError(t, err, "UAA: service not found")
+
+	var broken struct {
+		Broken []testHANAConfig `xsenv:"group:broken"`
+	}
+	assert.ErrorContains(t, env.BindAll(&broken), "Broken: broken: ")
+
+	var notSlice struct {
+		DB testHANAConfig `xsenv:"group:hana"`
+	}
+	assert.ErrorIs(t, env.BindAll(&notSlice), ErrInvalidTarget)
+	assert.ErrorIs(t, env.BindAll(config), ErrInvalidTarget)
+}
@@ -0,0 +1,91 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoadEnvFromServiceBindingRoot loads the service bindings of the Service Binding Specification for Kubernetes
+// from the directory the SERVICE_BINDING_ROOT variable points to.
+// Every directory below the root is a binding named like the directory. Its type file becomes the label
+// and the group of the service, its provider file the provider and every other file a credential.
+// Hidden files, like the ones of mounted Kubernetes secrets, are ignored.
+// If the variable is not set, the returned error wraps os.ErrNotExist.
+func LoadEnvFromServiceBindingRoot(opts ...Option) (*Env, error) {
+	root, ok := os.LookupEnv(ServiceBindingRootKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not set: %w", ServiceBindingRootKey, os.ErrNotExist)
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]*json.RawMessage)
+	for _, entry := range entries {
+		dir := filepath.Join(root, entry.Name())
+		if strings.HasPrefix(entry.Name(), ".") || !isDir(dir) {
+			continue
+		}
+		group, service, err := readBinding(entry.Name(), dir)
+		if err != nil {
+			return nil, err
+		}
+		groups[group] = append(groups[group], service)
+	}
+	return indexServices(groups, KubernetesSource, newOptions(opts))
+}
+
+// readBinding reads the binding directory dir and returns the synthesized service with its group.
+func readBinding(name, dir string) (string, *json.RawMessage, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	service := struct {
+		Name        string            `json:"name"`
+		Label       string            `json:"label,omitempty"`
+		Provider    string            `json:"provider,omitempty"`
+		Credentials map[string]string `json:"credentials"`
+	}{Name: name, Credentials: make(map[string]string)}
+
+	// ReadDir returns the entries sorted by name
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if strings.HasPrefix(entry.Name(), ".") || isDir(path) {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", nil, err
+		}
+		switch entry.Name() {
+		case "type":
+			service.Label = strings.TrimSpace(string(data))
+		case "provider":
+			service.Provider = strings.TrimSpace(string(data))
+		default:
+			service.Credentials[entry.Name()] = string(data)
+		}
+	}
+
+	data, err := json.Marshal(service)
+	if err != nil {
+		return "", nil, err
+	}
+	group := service.Label
+	if group == "" {
+		group = defaultGroup
+	}
+	msg := json.RawMessage(data)
+	return group, &msg, nil
+}
+
+// isDir reports whether path is a directory, following symbolic links.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
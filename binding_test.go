@@ -0,0 +1,56 @@
+package xsenv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeBinding(t *testing.T, root, name string, files map[string]string) {
+	t.Helper()
+	dir := filepath.Join(root, name)
+	assert.NoError(t, os.MkdirAll(dir, 0o700))
+	for file, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(content), 0o600))
+	}
+}
+
+func TestLoadEnvFromServiceBindingRoot(t *testing.T) {
+	root := t.TempDir()
+	writeBinding(t, root, "orders-db", map[string]string{
+		"type":     "postgresql\n",
+		"provider": "bitnami",
+		"host":     "db.example.com",
+		"password": "secret",
+		".hidden":  "ignored",
+	})
+	writeBinding(t, root, "cache", map[string]string{"host": "cache.example.com"})
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "README"), []byte("not a binding"), 0o600))
+
+	_, err := LoadEnvFromServiceBindingRoot()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	t.Setenv(ServiceBindingRootKey, root)
+	env, err := LoadEnvFromServiceBindingRoot()
+	assert.NoError(t, err)
+	assert.Equal(t, KubernetesSource, env.Source)
+	assert.Equal(t, []string{"cache", "orders-db"}, env.Names())
+
+	raw, _ := env.Raw("orders-db")
+	assert.JSONEq(t, `{"name": "orders-db", "label": "postgresql", "provider": "bitnami",
+		"credentials": {"host": "db.example.com", "password": "secret"}}`, string(raw))
+	assert.NoError(t, env.AssertLabel("orders-db", "postgresql"))
+
+	configs, err := LoadGroup[map[string]string](env, defaultGroup)
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]string{{"host": "cache.example.com"}}, configs)
+
+	_, err = LoadEnvFromServiceBindingRoot(WithMinServices(3))
+	assert.ErrorIs(t, err, ErrTooFewServices)
+
+	t.Setenv(ServiceBindingRootKey, filepath.Join(root, "missing"))
+	_, err = LoadEnvFromServiceBindingRoot()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
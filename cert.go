@@ -0,0 +1,35 @@
+package xsenv
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidCertificate indicates that a certificate or key field does not hold PEM data.
+var ErrInvalidCertificate = errors.New("invalid certificate")
+
+// DecodeCert returns the PEM bytes of a certificate or key field.
+// Bindings deliver these either as PEM, possibly with escaped newlines ("\n"),
+// or as base64 encoded PEM. Both forms are accepted.
+// It returns ErrInvalidCertificate if s holds no PEM block in either form.
+func DecodeCert(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "-----BEGIN") {
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+		if err != nil {
+			return nil, errors.Join(ErrInvalidCertificate, err)
+		}
+		s = strings.TrimSpace(string(decoded))
+	}
+	data := []byte(strings.ReplaceAll(s, `\n`, "\n"))
+	if block, _ := pem.Decode(data); block == nil {
+		return nil, ErrInvalidCertificate
+	}
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	return data, nil
+}
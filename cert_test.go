@@ -0,0 +1,37 @@
+package xsenv
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPEM = "-----BEGIN CERTIFICATE-----\nMIIBkTCB+wIJAKHHIGQ=\n-----END CERTIFICATE-----\n"
+
+func TestDecodeCert(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{"pem", testPEM},
+		{"pem without trailing newline", strings.TrimSuffix(testPEM, "\n")},
+		{"escaped newlines", strings.ReplaceAll(testPEM, "\n", `\n`)},
+		{"base64", base64.StdEncoding.EncodeToString([]byte(testPEM))},
+		{"wrapped base64", "  " + base64.StdEncoding.EncodeToString([]byte(testPEM))[:20] + "\n" +
+			base64.StdEncoding.EncodeToString([]byte(testPEM))[20:] + "\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := DecodeCert(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, testPEM, string(data))
+		})
+	}
+
+	for _, input := range []string{"", "not base64!", base64.StdEncoding.EncodeToString([]byte("no pem"))} {
+		_, err := DecodeCert(input)
+		assert.ErrorIs(t, err, ErrInvalidCertificate, input)
+	}
+}
@@ -7,17 +7,6 @@ import (
 	"github.com/darmiel/go-xsenv"
 )
 
-func Must[T any](t T, err error) T {
-	MustErr(err)
-	return t
-}
-
-func MustErr(err error) {
-	if err != nil {
-		panic(err)
-	}
-}
-
 // UAAConfig is some arbitrary configuration struct.
 type UAAConfig struct {
 	ClientID  string `json:"clientid"`
@@ -54,7 +43,7 @@ func (u *UAAConfig) UnmarshalService(message *json.RawMessage) error {
 }
 
 func main() {
-	env := Must(xsenv.LoadEnv())
+	env := xsenv.MustLoadEnv()
 	fmt.Println(env.ServicesByName)
 
 	var uaa UAAConfig
@@ -0,0 +1,256 @@
+package xsenv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// decodeService decodes the service msg into target.
+// If target implements UnmarshalService, it is used. Otherwise, the credentials
+// of the service, or the whole service if it has no credentials, are passed to
+// UnmarshalCredentials if target implements it, or decoded into target.
+func decodeService(target any, msg *json.RawMessage) error {
+	if u, ok := target.(UnmarshalService); ok {
+		return u.UnmarshalService(msg)
+	}
+	data, err := decodedData(msg)
+	if err != nil {
+		return err
+	}
+	if u, ok := target.(UnmarshalCredentials); ok {
+		return u.UnmarshalCredentials(&data)
+	}
+	return json.Unmarshal(data, target)
+}
+
+// decodedData returns the part of the service msg decoded into plain targets:
+// its credentials, or the whole service if it has no credentials.
+func decodedData(msg *json.RawMessage) (json.RawMessage, error) {
+	var parsed struct {
+		Credentials json.RawMessage `json:"credentials"`
+	}
+	if err := json.Unmarshal(*msg, &parsed); err != nil {
+		return nil, err
+	}
+	if parsed.Credentials != nil {
+		return parsed.Credentials, nil
+	}
+	return *msg, nil
+}
+
+// decode decodes the service stored under key into target, selecting the section configured using
+// WithEnvironmentSection and applying the field transformer configured using WithFieldTransformer first.
+func (e *Env) decode(target any, key string) error {
+	msg, err := e.prepare(key)
+	if err != nil {
+		return err
+	}
+	if err := decodeService(target, msg); err != nil {
+		e.metrics().IncUnmarshalError(key)
+		return err
+	}
+	return nil
+}
+
+// prepare returns the service stored under key as it is decoded, with the credentials moved from the key
+// configured using WithCredentialsKey and unquoted using WithUnquoteCredentials, the section configured using
+// WithEnvironmentSection selected and the field transformer configured using WithFieldTransformer applied.
+func (e *Env) prepare(key string) (*json.RawMessage, error) {
+	msg := e.ServicesByName[key]
+	if credentialsKey := e.opts.credentials(); credentialsKey != DefaultCredentialsKey {
+		moved, err := withStandardCredentials(msg, credentialsKey)
+		if err != nil {
+			return nil, err
+		}
+		msg = moved
+	}
+	if e.opts != nil && e.opts.unquoteCredentials {
+		unquoted, err := unquoteCredentials(msg)
+		if err != nil {
+			return nil, err
+		}
+		msg = unquoted
+	}
+	if e.opts != nil && e.opts.environmentSection != "" {
+		selected, err := selectSection(msg, e.opts.environmentSection)
+		if err != nil {
+			return nil, err
+		}
+		msg = selected
+	}
+	if e.opts != nil && e.opts.fieldTransformer != nil {
+		transformed, err := transformFields(*msg, e.opts.fieldTransformer)
+		if err != nil {
+			return nil, err
+		}
+		msg = &transformed
+	}
+	return msg, nil
+}
+
+// transformFields applies transform to every string leaf of the JSON document data.
+// Everything else, including numbers and the structure of the document, is left as is.
+func transformFields(data json.RawMessage, transform func(path, value string) string) (json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(transformValue("", doc, transform))
+}
+
+// transformValue walks value, calling transform for every string with its dot separated path.
+func transformValue(path string, value any, transform func(path, value string) string) any {
+	switch v := value.(type) {
+	case string:
+		return transform(path, v)
+	case map[string]any:
+		for key, child := range v {
+			v[key] = transformValue(joinPath(path, key), child, transform)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = transformValue(joinPath(path, strconv.Itoa(i)), child, transform)
+		}
+	}
+	return value
+}
+
+// joinPath appends the segment to the dot separated path.
+func joinPath(path, segment string) string {
+	if path == "" {
+		return segment
+	}
+	return path + "." + segment
+}
+
+// LoadGroup decodes every service of a VCAP_SERVICES group (e.g. "hana") into a slice of T,
+// keeping the order of the configuration.
+// If *T implements UnmarshalService it is used, otherwise the credentials of each service are decoded into T.
+// An absent group results in an empty slice and no error.
+func LoadGroup[T any](env *Env, group string) ([]T, error) {
+	keys := env.groups[group]
+	result := make([]T, 0, len(keys))
+	for _, key := range keys {
+		var value T
+		if err := env.decode(&value, key); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+// LoadGroupMap is like LoadGroup but returns the decoded services keyed by their names,
+// e.g. to look up one of several database instances by name later.
+// By default, a service failing to decode fails the whole group. Using WithSkipDecodeErrors,
+// such services are left out of the map instead, logging a warning if env was loaded using WithLogger.
+func LoadGroupMap[T any](env *Env, group string, opts ...CallOption) (map[string]T, error) {
+	o := newCallOptions(opts)
+	keys := env.groups[group]
+	result := make(map[string]T, len(keys))
+	for _, key := range keys {
+		var value T
+		if err := env.decode(&value, key); err != nil {
+			if o.skipDecodeErrors {
+				env.opts.warn("skipping service failing to decode", "group", group, "name", key, "error", err)
+				continue
+			}
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+// Load decodes the service with the given name into a new value of type T, using the same rules as Env.LoadService.
+// Credentials are decoded using encoding/json, so T and its nested fields may implement json.Unmarshaler,
+// e.g. to parse a time.Duration from a string.
+func Load[T any](env *Env, name string) (T, error) {
+	var value T
+	err := env.LoadService(&value, name)
+	return value, err
+}
+
+// LoadOrDefault is like Load but returns def if the service does not exist, e.g. for optional services.
+// Other errors, like a service failing to decode, are returned as usual.
+func LoadOrDefault[T any](env *Env, name string, def T) (T, error) {
+	if _, ok := env.resolve(name); !ok {
+		return def, nil
+	}
+	return Load[T](env, name)
+}
+
+// Get is like Load, but if the env was loaded using WithDecodeCache, the decoded value is cached
+// per service name and type, so subsequent calls return it without decoding again.
+// Cached values are shared between callers and invalidated when the service is changed using Set or Remove.
+func Get[T any](env *Env, name string) (T, error) {
+	if env.cache == nil {
+		return Load[T](env, name)
+	}
+	key, ok := env.resolve(name)
+	if !ok {
+		env.metrics().IncNotFound(name)
+		var zero T
+		return zero, ErrServiceNotFound
+	}
+
+	ck := cacheKey{key: key, typ: reflect.TypeFor[T]()}
+	if value, ok := env.cache.get(ck); ok {
+		return value.(T), nil
+	}
+	value, err := Load[T](env, key)
+	if err != nil {
+		return value, err
+	}
+	env.cache.put(ck, value)
+	return value, nil
+}
+
+// cacheKey identifies a decoded value by service key and target type.
+type cacheKey struct {
+	key string
+	typ reflect.Type
+}
+
+// decodeCache holds decoded service values, see Get.
+type decodeCache struct {
+	mu     sync.Mutex
+	values map[cacheKey]any
+}
+
+func (c *decodeCache) get(key cacheKey) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.values[key]
+	return value, ok
+}
+
+func (c *decodeCache) put(key cacheKey, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.values == nil {
+		c.values = make(map[cacheKey]any)
+	}
+	c.values[key] = value
+}
+
+// invalidate removes all values decoded from the service stored under key.
+// It is a no-op on a nil cache.
+func (c *decodeCache) invalidate(key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for ck := range c.values {
+		if ck.key == key {
+			delete(c.values, ck)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testHANAConfig struct {
+	Host string `json:"host"`
+	Port string `json:"port"`
+}
+
+type testNamedService struct {
+	Name string
+}
+
+func (s *testNamedService) UnmarshalService(msg *json.RawMessage) error {
+	parsed := struct {
+		Name string `json:"name"`
+	}{}
+	if err := json.Unmarshal(*msg, &parsed); err != nil {
+		return err
+	}
+	if parsed.Name == "" {
+		return MissingFieldError("name")
+	}
+	s.Name = parsed.Name
+	return nil
+}
+
+const testGroupEnv = `{"VCAP_SERVICES": {
+	"hana": [
+		{"name": "db-1", "credentials": {"host": "one.example.com", "port": "30015"}},
+		{"name": "db-2", "credentials": {"host": "two.example.com", "port": "30041"}}
+	],
+	"broken": [{"name": "broken", "credentials": {"host": 42}}]
+}}`
+
+// testDuration decodes a time.Duration from its string representation.
+type testDuration struct {
+	time.Duration
+}
+
+func (d *testDuration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+func TestLoadWithUnmarshaler(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"redis": [
+		{"name": "cache", "credentials": {"host": "cache.example.com", "timeout": "1m30s", "retries": {"backoff": "250ms"}}},
+		{"name": "broken", "credentials": {"timeout": "soon"}}
+	]}}`), RawSource)
+	assert.NoError(t, err)
+
+	type config struct {
+		Host    string       `json:"host"`
+		Timeout testDuration `json:"timeout"`
+		Retries struct {
+			Backoff *testDuration `json:"backoff"`
+		} `json:"retries"`
+	}
+	cfg, err := Load[config](env, "cache")
+	assert.NoError(t, err)
+	assert.Equal(t, "cache.example.com", cfg.Host)
+	assert.Equal(t, 90*time.Second, cfg.Timeout.Duration)
+	assert.Equal(t, 250*time.Millisecond, cfg.Retries.Backoff.Duration)
+
+	_, err = Load[config](env, "broken")
+	assert.ErrorContains(t, err, "soon")
+}
+
+func TestLoadGroup(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(testGroupEnv), RawSource)
+	assert.NoError(t, err)
+
+	configs, err := LoadGroup[testHANAConfig](env, "hana")
+	assert.NoError(t, err)
+	assert.Equal(t, []testHANAConfig{
+		{Host: "one.example.com", Port: "30015"},
+		{Host: "two.example.com", Port: "30041"},
+	}, configs)
+
+	// UnmarshalService implementations are used when available
+	named, err := LoadGroup[testNamedService](env, "hana")
+	assert.NoError(t, err)
+	assert.Equal(t, []testNamedService{{Name: "db-1"}, {Name: "db-2"}}, named)
+
+	// absent groups are empty
+	configs, err = LoadGroup[testHANAConfig](env, "nonexistent")
+	assert.NoError(t, err)
+	assert.Empty(t, configs)
+	assert.NotNil(t, configs)
+
+	// decoding errors name the failing service
+	_, err = LoadGroup[testHANAConfig](env, "broken")
+	assert.ErrorContains(t, err, "broken: ")
+}
+
+func TestLoadGroupMap(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"hana": [
+		{"name": "db-1", "credentials": {"host": "one.example.com", "port": "30015"}},
+		{"name": "db-2", "credentials": {"host": "two.example.com", "port": "30041"}},
+		{"name": "db-3", "credentials": {"host": 3}}
+	]}}`), RawSource)
+	assert.NoError(t, err)
+
+	_, err = LoadGroupMap[testHANAConfig](env, "hana")
+	assert.ErrorContains(t, err, "db-3: ")
+
+	configs, err := LoadGroupMap[testHANAConfig](env, "hana", WithSkipDecodeErrors())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]testHANAConfig{
+		"db-1": {Host: "one.example.com", Port: "30015"},
+		"db-2": {Host: "two.example.com", Port: "30041"},
+	}, configs)
+
+	configs, err = LoadGroupMap[testHANAConfig](env, "nonexistent")
+	assert.NoError(t, err)
+	assert.Empty(t, configs)
+	assert.NotNil(t, configs)
+}
+
+func TestDecodeServiceWithoutCredentials(t *testing.T) {
+	msg := json.RawMessage(`{"name": "plain", "host": "example.com"}`)
+	var config testHANAConfig
+	assert.NoError(t, decodeService(&config, &msg))
+	assert.Equal(t, "example.com", config.Host)
+}
+
+// countingService counts how often it was unmarshaled.
+type countingService struct {
+	Name string
+}
+
+var countingCalls int
+
+func (c *countingService) UnmarshalService(msg *json.RawMessage) error {
+	countingCalls++
+	var named testNamedService
+	if err := named.UnmarshalService(msg); err != nil {
+		return err
+	}
+	c.Name = named.Name
+	return nil
+}
+
+func TestLoad(t *testing.T) {
+	env, _ := loadEnvFromBytes([]byte(testGroupEnv), RawSource)
+
+	config, err := Load[testHANAConfig](env, "DB-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "one.example.com", config.Host)
+
+	_, err = Load[testHANAConfig](env, "nonexistent")
+	assert.ErrorIs(t, err, ErrServiceNotFound)
+}
+
+func TestLoadOrDefault(t *testing.T) {
+	env, _ := loadEnvFromBytes([]byte(testGroupEnv), RawSource)
+	def := testHANAConfig{Host: "localhost", Port: "30015"}
+
+	config, err := LoadOrDefault(env, "db-2", def)
+	assert.NoError(t, err)
+	assert.Equal(t, "two.example.com", config.Host)
+
+	config, err = LoadOrDefault(env, "nonexistent", def)
+	assert.NoError(t, err)
+	assert.Equal(t, def, config)
+
+	_, err = LoadOrDefault(env, "broken", def)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrServiceNotFound)
+}
+
+func TestGet(t *testing.T) {
+	t.Run("cached", func(t *testing.T) {
+		env, _ := loadEnvFromBytes([]byte(testGroupEnv), RawSource, WithDecodeCache())
+		countingCalls = 0
+
+		for i := 0; i < 3; i++ {
+			value, err := Get[countingService](env, "db-1")
+			assert.NoError(t, err)
+			assert.Equal(t, "db-1", value.Name)
+		}
+		assert.Equal(t, 1, countingCalls)
+
+		// different types are cached separately
+		config, err := Get[testHANAConfig](env, "db-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "one.example.com", config.Host)
+		assert.Equal(t, 1, countingCalls)
+
+		// changes invalidate the cache
+		assert.NoError(t, env.Set("db-1", map[string]string{"host": "new.example.com"}))
+		config, err = Get[testHANAConfig](env, "db-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "new.example.com", config.Host)
+		_, _ = Get[countingService](env, "db-1")
+		assert.Equal(t, 2, countingCalls)
+
+		assert.True(t, env.Remove("db-1"))
+		_, err = Get[testHANAConfig](env, "db-1")
+		assert.ErrorIs(t, err, ErrServiceNotFound)
+	})
+
+	t.Run("uncached", func(t *testing.T) {
+		env, _ := loadEnvFromBytes([]byte(testGroupEnv), RawSource)
+		countingCalls = 0
+		for i := 0; i < 3; i++ {
+			_, err := Get[countingService](env, "db-1")
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, 3, countingCalls)
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		env, _ := loadEnvFromBytes([]byte(testGroupEnv), RawSource, WithDecodeCache())
+		_, err := Get[testHANAConfig](env, "broken")
+		assert.Error(t, err)
+		assert.NoError(t, env.Set("broken", map[string]string{"host": "fixed"}))
+		config, err := Get[testHANAConfig](env, "broken")
+		assert.NoError(t, err)
+		assert.Equal(t, "fixed", config.Host)
+	})
+}
+
+func TestWithFieldTransformer(t *testing.T) {
+	data := []byte(`{"VCAP_SERVICES": {"xsuaa": [{"name": "uaa", "tags": [" a "], "credentials": {
+		"url": "https://example.com/ ", "clientid": " sb-uaa", "port": 443, "nested": {"url": "https://nested.com//"}
+	}}]}}`)
+	var paths []string
+	env, err := loadEnvFromBytes(data, RawSource, WithFieldTransformer(func(path, value string) string {
+		paths = append(paths, path)
+		value = strings.TrimSpace(value)
+		if strings.HasSuffix(path, "url") {
+			value = strings.TrimRight(value, "/")
+		}
+		return value
+	}))
+	assert.NoError(t, err)
+
+	var creds struct {
+		URL      string         `json:"url"`
+		ClientID string         `json:"clientid"`
+		Port     int            `json:"port"`
+		Nested   map[string]any `json:"nested"`
+	}
+	assert.NoError(t, env.LoadService(&creds, "uaa"))
+	assert.Equal(t, "https://example.com", creds.URL)
+	assert.Equal(t, "sb-uaa", creds.ClientID)
+	assert.Equal(t, 443, creds.Port)
+	assert.Equal(t, "https://nested.com", creds.Nested["url"])
+	assert.ElementsMatch(t, []string{"name", "tags.0", "credentials.url", "credentials.clientid", "credentials.nested.url"}, paths)
+
+	// UnmarshalService implementations see the transformed service as well
+	var named testNamedService
+	assert.NoError(t, env.LoadService(&named, "uaa"))
+
+	// the stored service is left untouched
+	raw, _ := env.Raw("uaa")
+	assert.Contains(t, string(raw), `"https://example.com/ "`)
+}
+
+// testCredentials implements UnmarshalCredentials, requiring a host.
+type testCredentials struct {
+	Host string
+}
+
+func (c *testCredentials) UnmarshalCredentials(msg *json.RawMessage) error {
+	parsed := struct {
+		Host string `json:"host"`
+	}{}
+	if err := json.Unmarshal(*msg, &parsed); err != nil {
+		return err
+	}
+	if parsed.Host == "" {
+		return MissingFieldError("host")
+	}
+	c.Host = parsed.Host
+	return nil
+}
+
+// testBoth implements both UnmarshalService and UnmarshalCredentials.
+type testBoth struct {
+	testNamedService
+	testCredentials
+}
+
+func (b *testBoth) UnmarshalService(msg *json.RawMessage) error {
+	return b.testNamedService.UnmarshalService(msg)
+}
+
+func TestUnmarshalCredentials(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(testGroupEnv), RawSource)
+	assert.NoError(t, err)
+
+	var creds testCredentials
+	assert.NoError(t, env.LoadService(&creds, "db-1"))
+	assert.Equal(t, "one.example.com", creds.Host)
+
+	group, err := LoadGroup[testCredentials](env, "hana")
+	assert.NoError(t, err)
+	assert.Equal(t, []testCredentials{{Host: "one.example.com"}, {Host: "two.example.com"}}, group)
+
+	// UnmarshalService takes precedence
+	var both testBoth
+	assert.NoError(t, env.LoadService(&both, "db-2"))
+	assert.Equal(t, "db-2", both.Name)
+	assert.Empty(t, both.Host)
+
+	assert.Error(t, env.LoadService(&creds, "broken"))
+}
@@ -0,0 +1,58 @@
+package xsenv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// ErrUnexpectedSource indicates that the configuration was loaded from a source which is not allowed, see Env.RequireSource.
+var ErrUnexpectedSource = errors.New("unexpected configuration source")
+
+// ServiceBindingRootKey is the environment variable pointing to the directory holding the
+// service bindings on Kubernetes, as defined by the Service Binding Specification.
+const ServiceBindingRootKey = "SERVICE_BINDING_ROOT"
+
+// DetectSource reports the platform the configuration is provided by, judging by environment variables
+// and the default file, e.g. to log it or to branch on the platform before loading:
+// EnvironmentSource if the environment variable is set (see WithEnvKey),
+// KubernetesSource if the SERVICE_BINDING_ROOT variable is set,
+// FileSource if the default file exists and RawSource otherwise.
+// It does not report what LoadEnv will use, as LoadEnv never reads the service binding root,
+// use LoadEnvFromServiceBindingRoot if KubernetesSource is reported:
+//
+//	if xsenv.DetectSource() == xsenv.KubernetesSource {
+//		env, err = xsenv.LoadEnvFromServiceBindingRoot()
+//	}
+func DetectSource(opts ...Option) Source {
+	o := newOptions(opts)
+	if _, ok := os.LookupEnv(o.envKey); ok {
+		return EnvironmentSource
+	}
+	if _, ok := os.LookupEnv(ServiceBindingRootKey); ok {
+		return KubernetesSource
+	}
+	if _, err := os.Stat(DefaultEnvFile); err == nil {
+		return FileSource
+	}
+	return RawSource
+}
+
+// RequireSource returns ErrUnexpectedSource if the Source of e is not one of allowed,
+// e.g. to refuse starting in production with a configuration loaded from a local file:
+//
+//	if err := env.RequireSource(xsenv.EnvironmentSource); err != nil {
+//		log.Fatal(err)
+//	}
+func (e *Env) RequireSource(allowed ...Source) error {
+	if slices.Contains(allowed, e.Source) {
+		return nil
+	}
+	names := make([]string, len(allowed))
+	for i, source := range allowed {
+		names[i] = string(source)
+	}
+	return fmt.Errorf("%w: loaded from %q, allowed are %s", ErrUnexpectedSource, e.Source, strings.Join(names, ", "))
+}
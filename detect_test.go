@@ -0,0 +1,40 @@
+package xsenv
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectSource(t *testing.T) {
+	// the package directory holds the default file
+	assert.Equal(t, FileSource, DetectSource())
+
+	t.Setenv(ServiceBindingRootKey, t.TempDir())
+	assert.Equal(t, KubernetesSource, DetectSource())
+
+	t.Setenv(EnvironmentKey, `{}`)
+	assert.Equal(t, EnvironmentSource, DetectSource())
+	assert.Equal(t, KubernetesSource, DetectSource(WithEnvKey("CUSTOM_VCAP")))
+
+	assert.NoError(t, os.Unsetenv(ServiceBindingRootKey))
+	wd, _ := os.Getwd()
+	assert.NoError(t, os.Chdir(t.TempDir()))
+	defer func() {
+		_ = os.Chdir(wd)
+	}()
+	assert.Equal(t, RawSource, DetectSource(WithEnvKey("CUSTOM_VCAP")))
+}
+
+func TestRequireSource(t *testing.T) {
+	env := &Env{Source: FileSource}
+	assert.NoError(t, env.RequireSource(FileSource))
+	assert.NoError(t, env.RequireSource(EnvironmentSource, FileSource))
+
+	err := env.RequireSource(EnvironmentSource, KubernetesSource)
+	assert.ErrorIs(t, err, ErrUnexpectedSource)
+	assert.EqualError(t, err, `unexpected configuration source: loaded from "file", allowed are environment, kubernetes`)
+
+	assert.ErrorIs(t, env.RequireSource(), ErrUnexpectedSource)
+}
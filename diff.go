@@ -0,0 +1,100 @@
+package xsenv
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// EnvDiff describes the differences between two environments.
+// All name lists are sorted.
+type EnvDiff struct {
+	// OnlyInA holds the names of services only present in the first environment.
+	OnlyInA []string
+	// OnlyInB holds the names of services only present in the second environment.
+	OnlyInB []string
+	// Changed holds the names of services present in both environments with differing configurations.
+	Changed []string
+}
+
+// Empty reports whether the diff holds no differences.
+func (d EnvDiff) Empty() bool {
+	return len(d.OnlyInA) == 0 && len(d.OnlyInB) == 0 && len(d.Changed) == 0
+}
+
+// Diff compares the services of a and b. Services are matched by their normalized names
+// and compared by their normalized JSON, ignoring key order and whitespace.
+func Diff(a, b *Env) EnvDiff {
+	var diff EnvDiff
+	for _, key := range a.sortedKeys() {
+		other, ok := b.lookup(key)
+		if !ok {
+			diff.OnlyInA = append(diff.OnlyInA, key)
+			continue
+		}
+		if !jsonEqual(*a.ServicesByName[key], *b.ServicesByName[other]) {
+			diff.Changed = append(diff.Changed, key)
+		}
+	}
+	for _, key := range b.sortedKeys() {
+		if _, ok := a.lookup(key); !ok {
+			diff.OnlyInB = append(diff.OnlyInB, key)
+		}
+	}
+	return diff
+}
+
+// canonicalJSON re-encodes data with sorted keys and without insignificant whitespace.
+func canonicalJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// jsonEqual reports whether a and b hold semantically equal JSON.
+// Invalid JSON is compared byte by byte.
+func jsonEqual(a, b []byte) bool {
+	ca, errA := canonicalJSON(a)
+	cb, errB := canonicalJSON(b)
+	if errA != nil || errB != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(ca, cb)
+}
+
+// Equal reports whether e and other hold the same services, matched by their normalized names
+// and compared by their normalized JSON, see Diff.
+// Metadata like Source and ServiceSources, as well as the groups of the services, are ignored.
+func (e *Env) Equal(other *Env) bool {
+	if e == nil || other == nil {
+		return e == other
+	}
+	return Diff(e, other).Empty()
+}
+
+// Fingerprint returns a stable hash of the services of e, e.g. to log it at startup and detect configuration
+// drift between deployments. Like Equal, it depends on the normalized names and the normalized JSON of the
+// services only, so environments which are Equal have the same fingerprint, regardless of key order,
+// whitespace and metadata. It is the hex encoded SHA-256 of the canonical JSON object of all services.
+func (e *Env) Fingerprint() string {
+	services := make(map[string]json.RawMessage, len(e.ServicesByName))
+	for key, msg := range e.ServicesByName {
+		if msg == nil {
+			continue
+		}
+		canonical, err := canonicalJSON(*msg)
+		if err != nil {
+			// invalid JSON is hashed as is
+			canonical, _ = json.Marshal(string(*msg))
+		}
+		services[e.normalize(key)] = canonical
+	}
+	data, _ := json.Marshal(services)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
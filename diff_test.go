@@ -0,0 +1,85 @@
+package xsenv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	local, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {
+		"xsuaa": [{"name": "uaa", "credentials": {"clientid": "a", "url": "https://example.com"}}],
+		"hana": [{"name": "db", "credentials": {"host": "localhost"}}],
+		"user-provided": [{"name": "local-only"}]
+	}}`), FileSource)
+	assert.NoError(t, err)
+	deployed, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {
+		"xsuaa": [{"credentials": {"url": "https://example.com",
+			"clientid": "a"}, "name": "uaa"}],
+		"hana": [{"name": "db", "credentials": {"host": "db.example.com"}}],
+		"user-provided": [{"name": "deployed-only"}]
+	}}`), EnvironmentSource)
+	assert.NoError(t, err)
+
+	diff := Diff(local, deployed)
+	assert.Equal(t, EnvDiff{
+		OnlyInA: []string{"local-only"},
+		OnlyInB: []string{"deployed-only"},
+		Changed: []string{"db"},
+	}, diff)
+	assert.False(t, diff.Empty())
+
+	assert.True(t, Diff(local, local).Empty())
+}
+
+func TestJSONEqual(t *testing.T) {
+	assert.True(t, jsonEqual([]byte(`{"a": 1, "b": [1, 2]}`), []byte(`{"b":[1,2],"a":1}`)))
+	assert.False(t, jsonEqual([]byte(`{"a": 1}`), []byte(`{"a": 2}`)))
+	assert.False(t, jsonEqual([]byte(`[1, 2]`), []byte(`[2, 1]`)))
+	assert.True(t, jsonEqual([]byte(`{invalid`), []byte(`{invalid`)))
+	assert.False(t, jsonEqual([]byte(`{invalid`), []byte(`{}`)))
+}
+
+func TestEnvEqual(t *testing.T) {
+	fromFile, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"xsuaa": [{"name": "uaa", "credentials": {"clientid": "a", "url": "https://example.com"}}]}}`), FileSource)
+	assert.NoError(t, err)
+	fromEnv, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"other": [
+		{"credentials": {"url": "https://example.com", "clientid": "a"}, "name": "uaa"}
+	]}}`), EnvironmentSource)
+	assert.NoError(t, err)
+	assert.True(t, fromFile.Equal(fromEnv))
+
+	// round trip through Marshal
+	data, err := fromFile.Marshal()
+	assert.NoError(t, err)
+	roundTrip, err := loadEnvFromBytes(data, RawSource)
+	assert.NoError(t, err)
+	assert.True(t, roundTrip.Equal(fromFile))
+
+	assert.NoError(t, fromEnv.Set("uaa", map[string]string{"clientid": "b"}))
+	assert.False(t, fromFile.Equal(fromEnv))
+	assert.False(t, fromFile.Equal(&Env{}))
+
+	var empty *Env
+	assert.True(t, empty.Equal(nil))
+	assert.False(t, empty.Equal(fromFile))
+}
+
+func TestFingerprint(t *testing.T) {
+	a, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"xsuaa": [
+		{"name": "uaa", "credentials": {"clientid": "a", "url": "https://example.com"}},
+		{"name": "db", "credentials": {"port": 30015}}
+	]}}`), FileSource)
+	assert.NoError(t, err)
+	b, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"hana": [{"credentials": {"port": 30015}, "name": "db"}],
+		"xsuaa": [{"credentials": {"url": "https://example.com",   "clientid": "a"}, "name": "uaa"}]}}`), EnvironmentSource)
+	assert.NoError(t, err)
+
+	assert.Len(t, a.Fingerprint(), 64)
+	assert.Equal(t, a.Fingerprint(), b.Fingerprint())
+	assert.Equal(t, a.Fingerprint(), a.Fingerprint())
+
+	assert.NoError(t, b.Set("uaa", map[string]string{"clientid": "b"}))
+	assert.NotEqual(t, a.Fingerprint(), b.Fingerprint())
+	assert.NotEqual(t, a.Fingerprint(), (&Env{}).Fingerprint())
+}
@@ -0,0 +1,120 @@
+package xsenv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LoadEnvFromDotEnv loads the environment configuration from the VCAP_SERVICES variable of a dotenv file,
+// e.g. a line like VCAP_SERVICES='{"VCAP_SERVICES": {...}}'.
+// Values may be unquoted, single-quoted (taken literally) or double-quoted (supporting escape sequences),
+// quoted values may span multiple lines. The variable name can be changed using WithEnvKey.
+func LoadEnvFromDotEnv(path string, opts ...Option) (*Env, error) {
+	data, err := newOptions(opts).readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values, err := parseDotEnv(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	key := newOptions(opts).envKey
+	value, ok := values[key]
+	if !ok {
+		return nil, fmt.Errorf("%s: %s is not set", path, key)
+	}
+	return loadEnvFromBytes([]byte(value), FileSource, opts...)
+}
+
+// parseDotEnv parses the variables of a dotenv file.
+func parseDotEnv(content string) (map[string]string, error) {
+	values := make(map[string]string)
+	line := 1
+	for len(content) > 0 {
+		// skip empty lines and leading whitespace
+		trimmed := strings.TrimLeft(content, " \t\r\n")
+		line += strings.Count(content[:len(content)-len(trimmed)], "\n")
+		content = trimmed
+		if content == "" {
+			break
+		}
+		if content[0] == '#' {
+			content = skipLine(content)
+			continue
+		}
+
+		end := strings.IndexAny(content, "=\n")
+		if end < 0 || content[end] != '=' {
+			return nil, fmt.Errorf("line %d: missing '='", line)
+		}
+		key := strings.TrimSpace(strings.TrimPrefix(content[:end], "export "))
+		if key == "" {
+			return nil, fmt.Errorf("line %d: missing variable name", line)
+		}
+		content = strings.TrimLeft(content[end+1:], " \t")
+
+		var value string
+		switch {
+		case strings.HasPrefix(content, `"`), strings.HasPrefix(content, `'`):
+			quote := content[0]
+			var (
+				b      strings.Builder
+				closed bool
+				i      = 1
+			)
+			for ; i < len(content); i++ {
+				c := content[i]
+				if c == quote {
+					closed = true
+					break
+				}
+				if c == '\\' && quote == '"' && i+1 < len(content) {
+					i++
+					switch content[i] {
+					case 'n':
+						b.WriteByte('\n')
+					case 'r':
+						b.WriteByte('\r')
+					case 't':
+						b.WriteByte('\t')
+					case '"', '\\', '$':
+						b.WriteByte(content[i])
+					default:
+						b.WriteByte('\\')
+						b.WriteByte(content[i])
+					}
+					continue
+				}
+				b.WriteByte(c)
+			}
+			if !closed {
+				return nil, fmt.Errorf("line %d: unterminated quoted value of %s", line, key)
+			}
+			line += strings.Count(content[:i], "\n")
+			value = b.String()
+			content = content[i+1:]
+			rest, _, _ := strings.Cut(content, "\n")
+			if rest = strings.TrimSpace(rest); rest != "" && rest[0] != '#' {
+				return nil, fmt.Errorf("line %d: unexpected characters after quoted value of %s", line, key)
+			}
+			content = skipLine(content)
+		default:
+			value, _, _ = strings.Cut(content, "\n")
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = value[:i]
+			}
+			value = strings.TrimSpace(value)
+			content = skipLine(content)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// skipLine returns content after the next line break.
+func skipLine(content string) string {
+	if _, rest, ok := strings.Cut(content, "\n"); ok {
+		return "\n" + rest
+	}
+	return ""
+}
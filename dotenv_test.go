@@ -0,0 +1,71 @@
+package xsenv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	values, err := parseDotEnv(`
+# comment
+PLAIN=value
+SPACED = value with spaces # trailing comment
+export EXPORTED=yes
+SINGLE='{"a": "b\n"}'
+DOUBLE="{\"a\": \"b\"}\tx # not a comment"
+MULTI='{
+  "a": 1
+}' # comment
+EMPTY=
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"PLAIN":    "value",
+		"SPACED":   "value with spaces",
+		"EXPORTED": "yes",
+		"SINGLE":   `{"a": "b\n"}`,
+		"DOUBLE":   "{\"a\": \"b\"}\tx # not a comment",
+		"MULTI":    "{\n  \"a\": 1\n}",
+		"EMPTY":    "",
+	}, values)
+
+	for _, invalid := range []string{
+		"NOEQUALS\n",
+		"=value\n",
+		`UNTERMINATED="value`,
+		`TRAILING="value" rest`,
+	} {
+		_, err := parseDotEnv(invalid)
+		assert.Error(t, err, invalid)
+	}
+
+	_, err = parseDotEnv("A=1\n\nB=\"x\ny\"\nINVALID\n")
+	assert.ErrorContains(t, err, "line 5")
+}
+
+func TestLoadEnvFromDotEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	content := `OTHER=1
+VCAP_SERVICES='{"VCAP_SERVICES": {"xsuaa": [{"name": "uaa"}]}}'
+CUSTOM_SERVICES="{\"VCAP_SERVICES\": {\"hana\": [{\"name\": \"db\"}]}}"
+`
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	env, err := LoadEnvFromDotEnv(path)
+	assert.NoError(t, err)
+	assert.Equal(t, FileSource, env.Source)
+	assert.Equal(t, []string{"uaa"}, env.Names())
+
+	env, err = LoadEnvFromDotEnv(path, WithEnvKey("CUSTOM_SERVICES"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db"}, env.Names())
+
+	_, err = LoadEnvFromDotEnv(path, WithEnvKey("MISSING"))
+	assert.ErrorContains(t, err, "MISSING is not set")
+
+	_, err = LoadEnvFromDotEnv(filepath.Join(t.TempDir(), "nonexistent"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,68 @@
+package xsenv
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// WithBase64 makes loading decode the configuration from standard base64 before parsing it.
+// Combined with WithGzip, this handles compressed configurations passed in environment variables.
+func WithBase64() Option {
+	return func(o *options) {
+		o.base64 = true
+	}
+}
+
+// WithGzip makes loading decompress the gzip compressed configuration before parsing it.
+// If WithBase64 is set as well, the data is base64 decoded first.
+// Loading fails if the data is not valid gzip.
+func WithGzip() Option {
+	return func(o *options) {
+		o.gzip = true
+	}
+}
+
+// decodeData decodes the configuration data as requested by WithBase64 and WithGzip.
+func (o *options) decodeData(data []byte) ([]byte, error) {
+	if o.base64 {
+		decoded := make([]byte, base64.StdEncoding.DecodedLen(len(data)))
+		n, err := base64.StdEncoding.Decode(decoded, bytes.TrimSpace(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 data: %w", err)
+		}
+		data = decoded[:n]
+	}
+	if o.gzip {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+		if data, err = io.ReadAll(o.limit(zr)); err != nil {
+			if errors.Is(err, ErrConfigTooLarge) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+	}
+	return data, nil
+}
+
+// wrapReader wraps r to decode the configuration as requested by WithBase64 and WithGzip
+// while it is read, see LoadEnvFromReaderStreaming.
+func (o *options) wrapReader(r io.Reader) (io.Reader, error) {
+	if o.base64 {
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	}
+	if o.gzip {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+		r = o.limit(zr)
+	}
+	return r, nil
+}
@@ -0,0 +1,60 @@
+package xsenv
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func gzipData(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write([]byte(data))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestWithGzipAndBase64(t *testing.T) {
+	const config = `{"VCAP_SERVICES": {"xsuaa": [{"name": "uaa"}]}}`
+	compressed := gzipData(t, config)
+	encoded := base64.StdEncoding.EncodeToString(compressed)
+
+	testCases := []struct {
+		name string
+		data []byte
+		opts []Option
+	}{
+		{"gzip", compressed, []Option{WithGzip()}},
+		{"base64", []byte(base64.StdEncoding.EncodeToString([]byte(config)) + "\n"), []Option{WithBase64()}},
+		{"base64 and gzip", []byte(encoded), []Option{WithGzip(), WithBase64()}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			env, err := LoadEnvFromReader(bytes.NewReader(tc.data), tc.opts...)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"uaa"}, env.Names())
+
+			env, err = LoadEnvFromReaderStreaming(bytes.NewReader(tc.data), tc.opts...)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"uaa"}, env.Names())
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := LoadEnvFromReader(bytes.NewReader([]byte(config)), WithGzip())
+		assert.ErrorContains(t, err, "invalid gzip data")
+		_, err = LoadEnvFromReaderStreaming(bytes.NewReader([]byte(config)), WithGzip())
+		assert.ErrorContains(t, err, "invalid gzip data")
+
+		_, err = LoadEnvFromReader(bytes.NewReader(compressed[:len(compressed)-4]), WithGzip())
+		assert.ErrorContains(t, err, "invalid gzip data")
+
+		_, err = LoadEnvFromReader(bytes.NewReader([]byte(config)), WithBase64())
+		assert.ErrorContains(t, err, "invalid base64 data")
+	})
+}
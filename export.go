@@ -0,0 +1,71 @@
+package xsenv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ExportEnv flattens the credentials of the service with the given name into environment variables,
+// e.g. to pass them to a subprocess only reading flat variables. Keys are the name of the service and
+// the path of the value, uppercased and joined by underscores, so credentials.uri of "portal-uaa" becomes
+// PORTAL_UAA_URI and credentials.db.hosts[0] becomes PORTAL_UAA_DB_HOSTS_0. Characters other than
+// letters and digits are replaced by underscores. Null values are exported as empty strings.
+// It returns ErrServiceNotFound if the service does not exist and ErrNoCredentials if it has no credentials.
+func (e *Env) ExportEnv(name string) (map[string]string, error) {
+	key, ok := e.resolve(name)
+	if !ok {
+		return nil, ErrServiceNotFound
+	}
+	creds, err := e.RawCredentials(key)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(creds))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	vars := make(map[string]string)
+	flattenEnv(vars, envName(key), value)
+	return vars, nil
+}
+
+// flattenEnv stores value in vars under prefix, descending into objects and arrays.
+func flattenEnv(vars map[string]string, prefix string, value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			flattenEnv(vars, prefix+"_"+envName(key), child)
+		}
+	case []any:
+		for i, child := range v {
+			flattenEnv(vars, prefix+"_"+strconv.Itoa(i), child)
+		}
+	case nil:
+		vars[prefix] = ""
+	case string:
+		vars[prefix] = v
+	default:
+		// json.Number and bool
+		vars[prefix] = fmt.Sprint(v)
+	}
+}
+
+// envName converts s to the name of an environment variable, uppercasing it and
+// replacing all characters but letters and digits by underscores.
+func envName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
@@ -0,0 +1,37 @@
+package xsenv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportEnv(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {
+		"xsuaa": [{"name": "portal-uaa", "credentials": {
+			"uri": "https://uaa.example.com", "clientid": "client",
+			"port": 443, "verify": true, "zone": null,
+			"db": {"hosts": ["a", "b"], "schema.name": "APP"}
+		}}],
+		"user-provided": [{"name": "plain"}]
+	}}`), FileSource)
+	assert.NoError(t, err)
+
+	vars, err := env.ExportEnv("Portal-UAA")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"PORTAL_UAA_URI":            "https://uaa.example.com",
+		"PORTAL_UAA_CLIENTID":       "client",
+		"PORTAL_UAA_PORT":           "443",
+		"PORTAL_UAA_VERIFY":         "true",
+		"PORTAL_UAA_ZONE":           "",
+		"PORTAL_UAA_DB_HOSTS_0":     "a",
+		"PORTAL_UAA_DB_HOSTS_1":     "b",
+		"PORTAL_UAA_DB_SCHEMA_NAME": "APP",
+	}, vars)
+
+	_, err = env.ExportEnv("plain")
+	assert.ErrorIs(t, err, ErrNoCredentials)
+	_, err = env.ExportEnv("missing")
+	assert.ErrorIs(t, err, ErrServiceNotFound)
+}
@@ -0,0 +1,89 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// CheckFieldsOf checks that the named fields of the struct v are set (non-zero).
+// Fields are matched by their json name; if no names are given, all fields are checked.
+// It builds the Fields map for CheckAllFields automatically, removing the need to write
+// a presence check for every field when implementing UnmarshalService.
+func CheckFieldsOf(v any, required ...string) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("check fields: %T is not a struct", v)
+	}
+
+	values := make(map[string]reflect.Value)
+	var names []string
+	for _, f := range structFields(rv.Type()) {
+		// an error means that the field is part of a nil embedded pointer, i.e. zero
+		fv, _ := rv.FieldByIndexErr(f.index)
+		values[f.name] = fv
+		names = append(names, f.name)
+	}
+	if len(required) == 0 {
+		required = names
+	}
+
+	fields := make(Fields, len(required))
+	for _, name := range required {
+		fv, ok := values[name]
+		fields[name] = ok && fv.IsValid() && !fv.IsZero()
+	}
+	return CheckAllFields(fields)
+}
+
+// RequireOneOf checks that at least one group of fields is fully present in fields,
+// e.g. that either "clientsecret" or both "certificate" and "key" are set.
+// If no group is satisfied, it returns an ErrFieldMissing error describing the missing fields of every group.
+// Without groups, nothing is required.
+func RequireOneOf(fields Fields, groups ...[]string) error {
+	if len(groups) == 0 {
+		return nil
+	}
+	descriptions := make([]string, 0, len(groups))
+	for _, group := range groups {
+		var missing []string
+		for _, name := range group {
+			if !fields[name] {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		descriptions = append(descriptions, strings.Join(missing, ", "))
+	}
+	return fmt.Errorf("%w: one of (%s)", ErrFieldMissing, strings.Join(descriptions, ") or ("))
+}
+
+// Require wraps inner, checking that the named json fields of the struct inner points to are set (non-zero)
+// after it was unmarshaled successfully, see CheckFieldsOf. This adds required fields to any implementation
+// of UnmarshalService without changing it:
+//
+//	err := env.LoadService(xsenv.Require(&creds, "clientid", "clientsecret"), "uaa")
+//
+// Missing fields are reported as an ErrFieldMissing error listing all of them.
+func Require(inner UnmarshalService, fields ...string) UnmarshalService {
+	return &requiredFields{inner: inner, fields: fields}
+}
+
+// requiredFields is the UnmarshalService returned by Require.
+type requiredFields struct {
+	inner  UnmarshalService
+	fields []string
+}
+
+func (r *requiredFields) UnmarshalService(msg *json.RawMessage) error {
+	if err := r.inner.UnmarshalService(msg); err != nil {
+		return err
+	}
+	return CheckFieldsOf(r.inner, r.fields...)
+}
@@ -0,0 +1,97 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckFieldsOf(t *testing.T) {
+	type Extra struct {
+		Zone string `json:"zone"`
+	}
+	type credentials struct {
+		*Extra
+		ClientID  string `json:"clientid"`
+		XSAppName string `json:"xsappname"`
+		Port      int    `json:"port"`
+		Untagged  string
+	}
+
+	creds := credentials{ClientID: "sb-portal", Port: 443}
+	assert.NoError(t, CheckFieldsOf(creds, "clientid", "port"))
+	assert.NoError(t, CheckFieldsOf(&creds, "clientid"))
+
+	err := CheckFieldsOf(&creds, "clientid", "xsappname")
+	assert.ErrorIs(t, err, ErrFieldMissing)
+	assert.EqualError(t, err, "field(s) missing: xsappname")
+
+	// fields of nil embedded pointers are missing
+	assert.EqualError(t, CheckFieldsOf(creds, "zone"), "field(s) missing: zone")
+	creds.Extra = &Extra{Zone: "eu12"}
+	assert.NoError(t, CheckFieldsOf(creds, "zone"))
+
+	// unknown names are reported as missing
+	assert.EqualError(t, CheckFieldsOf(creds, "unknown"), "field(s) missing: unknown")
+
+	// all fields are checked if none are named
+	err = CheckFieldsOf(creds)
+	assert.ErrorIs(t, err, ErrFieldMissing)
+	assert.Contains(t, err.Error(), "xsappname")
+	assert.Contains(t, err.Error(), "Untagged")
+	assert.NotContains(t, err.Error(), "clientid")
+
+	assert.Error(t, CheckFieldsOf("not a struct"))
+	assert.Error(t, CheckFieldsOf((*credentials)(nil)))
+}
+
+func TestRequireOneOf(t *testing.T) {
+	secret := []string{"clientsecret"}
+	certificate := []string{"certificate", "key"}
+
+	assert.NoError(t, RequireOneOf(Fields{"clientsecret": true}, secret, certificate))
+	assert.NoError(t, RequireOneOf(Fields{"certificate": true, "key": true}, secret, certificate))
+	assert.NoError(t, RequireOneOf(Fields{}))
+
+	err := RequireOneOf(Fields{"certificate": true, "key": false}, secret, certificate)
+	assert.ErrorIs(t, err, ErrFieldMissing)
+	assert.EqualError(t, err, "field(s) missing: one of (clientsecret) or (key)")
+
+	err = RequireOneOf(Fields{}, secret, certificate)
+	assert.EqualError(t, err, "field(s) missing: one of (clientsecret) or (certificate, key)")
+}
+
+type testRequiredConfig struct {
+	ClientID     string `json:"clientid"`
+	ClientSecret string `json:"clientsecret"`
+	Port         int    `json:"port"`
+}
+
+func (c *testRequiredConfig) UnmarshalService(msg *json.RawMessage) error {
+	data, err := decodedData(msg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, c)
+}
+
+func TestRequire(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"xsuaa": [
+		{"name": "uaa", "credentials": {"clientid": "client", "clientsecret": "secret"}},
+		{"name": "broken", "credentials": {"clientid": 42}}
+	]}}`), FileSource)
+	assert.NoError(t, err)
+
+	var config testRequiredConfig
+	assert.NoError(t, env.LoadService(Require(&config, "clientid", "clientsecret"), "uaa"))
+	assert.Equal(t, "secret", config.ClientSecret)
+
+	err = env.LoadService(Require(&config, "clientid", "port", "url"), "uaa")
+	assert.ErrorIs(t, err, ErrFieldMissing)
+	assert.EqualError(t, err, "field(s) missing: port, url")
+
+	// errors of the wrapped implementation are returned as is
+	var typeErr *json.UnmarshalTypeError
+	assert.ErrorAs(t, env.LoadService(Require(&testRequiredConfig{}, "clientid"), "broken"), &typeErr)
+}
@@ -0,0 +1,124 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrFileOutsideBaseDir indicates that a file referenced by a service lies outside the base directory
+// configured using WithFileReferences.
+var ErrFileOutsideBaseDir = errors.New("referenced file outside of base directory")
+
+// fileReferenceSuffixes are the suffixes of credential keys referencing files, see WithFileReferences.
+var fileReferenceSuffixes = []string{"_path", "_file"}
+
+// WithFileReferences makes loading resolve credentials referencing files, for bindings storing large values
+// like certificates externally. For every string credential with one of the given keys, which must end in
+// "_path" or "_file", the referenced file is read and its contents are stored under the key without the suffix,
+// e.g. with the key "certificate_path", {"certificate_path": "cert.pem"} gains a "certificate" field,
+// replacing a present value. Other credentials are left as they are, so regular fields like "context_path"
+// are not mistaken for files. Keys without one of the suffixes make loading fail.
+// Relative paths are resolved against baseDir, and files outside baseDir, also via symbolic links,
+// are rejected with ErrFileOutsideBaseDir. Unreadable files make loading fail.
+func WithFileReferences(baseDir string, keys ...string) Option {
+	return func(o *options) {
+		o.fileReferences = append(o.fileReferences, keys...)
+		o.fileBaseDir = baseDir
+	}
+}
+
+// resolveFileReferences stores the contents of the files referenced by the credentials of msg
+// in the credentials, see WithFileReferences.
+func (o *options) resolveFileReferences(msg json.RawMessage) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(msg, &fields); err != nil {
+		return nil, err
+	}
+	credentialsKey, raw := credentialsField(fields, o.credentials())
+	var creds map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &creds); err != nil || creds == nil {
+		// only credentials objects can reference files
+		return msg, nil
+	}
+
+	changed := false
+	for _, key := range o.fileReferences {
+		base, ok := trimFileReferenceSuffix(key)
+		if !ok {
+			return nil, fmt.Errorf("file reference %q: key must end in %s", key, strings.Join(fileReferenceSuffixes, " or "))
+		}
+		value, ok := creds[key]
+		if !ok {
+			continue
+		}
+		var path string
+		if err := json.Unmarshal(value, &path); err != nil || path == "" {
+			continue
+		}
+		contents, err := o.readFileReference(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		creds[base], _ = json.Marshal(string(contents))
+		changed = true
+	}
+	if !changed {
+		return msg, nil
+	}
+	var err error
+	if fields[credentialsKey], err = json.Marshal(creds); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// trimFileReferenceSuffix returns key without its file reference suffix,
+// the second return value is false if key does not reference a file.
+func trimFileReferenceSuffix(key string) (string, bool) {
+	for _, suffix := range fileReferenceSuffixes {
+		if base, ok := strings.CutSuffix(key, suffix); ok && base != "" {
+			return base, true
+		}
+	}
+	return "", false
+}
+
+// readFileReference reads the file at path, which must lie within the base directory.
+func (o *options) readFileReference(path string) ([]byte, error) {
+	base, err := filepath.Abs(o.fileBaseDir)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+	path = filepath.Clean(path)
+	// symbolic links are resolved, so they cannot point outside the base directory either
+	resolvedBase := base
+	if resolved, err := filepath.EvalSymlinks(base); err == nil {
+		resolvedBase = resolved
+	}
+	// paths outside the base directory are rejected before touching the file system,
+	// so errors do not reveal whether they exist
+	if !within(base, path) && !within(resolvedBase, path) {
+		return nil, fmt.Errorf("%w: %s", ErrFileOutsideBaseDir, path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, err
+	}
+	if !within(resolvedBase, resolved) {
+		return nil, fmt.Errorf("%w: %s", ErrFileOutsideBaseDir, path)
+	}
+	return os.ReadFile(resolved)
+}
+
+// within reports whether path lies within dir, comparing both lexically.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
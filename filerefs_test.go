@@ -0,0 +1,73 @@
+package xsenv
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithFileReferences(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "cert.pem"), []byte("-----BEGIN CERTIFICATE-----\n"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "password"), []byte("secret"), 0o600))
+
+	data := []byte(fmt.Sprintf(`{"VCAP_SERVICES": {"xsuaa": [
+		{"name": "uaa", "credentials": {"certificate_path": "cert.pem", "password_file": %q, "clientid": "client"}},
+		{"name": "plain", "credentials": {"path": "not-a-reference", "context_path": "/odata"}}
+	]}}`, filepath.Join(dir, "password")))
+	env, err := loadEnvFromBytes(data, RawSource, WithFileReferences(dir, "certificate_path", "password_file"))
+	assert.NoError(t, err)
+
+	var config struct {
+		Certificate     string `json:"certificate"`
+		CertificatePath string `json:"certificate_path"`
+		Password        string `json:"password"`
+		ClientID        string `json:"clientid"`
+	}
+	assert.NoError(t, env.LoadService(&config, "uaa"))
+	assert.Equal(t, "-----BEGIN CERTIFICATE-----\n", config.Certificate)
+	assert.Equal(t, "cert.pem", config.CertificatePath)
+	assert.Equal(t, "secret", config.Password)
+	assert.Equal(t, "client", config.ClientID)
+
+	// only the configured keys reference files
+	raw, err := env.RawCredentials("plain")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"path": "not-a-reference", "context_path": "/odata"}`, string(raw))
+
+	// references are not resolved by default
+	env, err = loadEnvFromBytes(data, RawSource)
+	assert.NoError(t, err)
+	config.Certificate = ""
+	assert.NoError(t, env.LoadService(&config, "uaa"))
+	assert.Empty(t, config.Certificate)
+
+	_, err = loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"xsuaa": [
+		{"name": "uaa", "credentials": {"certificate_path": "missing.pem"}}
+	]}}`), RawSource, WithFileReferences(dir, "certificate_path"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = loadEnvFromBytes(data, RawSource, WithFileReferences(dir, "certificate"))
+	assert.EqualError(t, err, `uaa: file reference "certificate": key must end in _path or _file`)
+}
+
+func TestWithFileReferencesTraversal(t *testing.T) {
+	root := t.TempDir()
+	base := filepath.Join(root, "secrets")
+	assert.NoError(t, os.Mkdir(base, 0o700))
+	outside := filepath.Join(root, "outside")
+	assert.NoError(t, os.WriteFile(outside, []byte("leaked"), 0o600))
+	assert.NoError(t, os.Symlink(outside, filepath.Join(base, "link")))
+
+	// paths outside the base directory are rejected whether they exist or not
+	for _, path := range []string{"../outside", outside, "link", "../missing", filepath.Join(root, "missing")} {
+		_, err := loadEnvFromBytes([]byte(fmt.Sprintf(`{"VCAP_SERVICES": {"xsuaa": [
+			{"name": "uaa", "credentials": {"key_file": %q}}
+		]}}`, path)), RawSource, WithFileReferences(base, "key_file"))
+		assert.ErrorIs(t, err, ErrFileOutsideBaseDir, path)
+		assert.NotErrorIs(t, err, os.ErrNotExist, path)
+	}
+}
@@ -0,0 +1,174 @@
+package xsenv
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// durationType is the type of time.Duration, parsed using time.ParseDuration.
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// BindFlags registers a flag in fs for every field of the struct target points to which is tagged with
+// `xsenv:"flag"`, named like the json name of the field, or `xsenv:"flag:name"` to choose another name.
+// The current values of the fields are used as defaults. Strings, booleans, integers, floats and
+// time.Duration are supported. Flags are applied by Env.LoadServiceWithFlagOverrides once fs was parsed.
+// It returns ErrInvalidTarget if target is not a non-nil pointer to a struct, a tagged field has an unsupported type
+// or is part of a nil embedded struct pointer.
+func BindFlags(fs *flag.FlagSet, target any) error {
+	fields, rv, err := flagFields(target)
+	if err != nil {
+		return err
+	}
+	for name, f := range fields {
+		field, err := rv.FieldByIndexErr(f.index)
+		if err != nil {
+			return fmt.Errorf("%s: %w: field of nil embedded struct", f.name, ErrInvalidTarget)
+		}
+		value := &flagValue{typ: f.typ, def: fmt.Sprint(field.Interface())}
+		if err := value.parse(value.def); err != nil {
+			return fmt.Errorf("%w: field %s of type %s cannot be bound to a flag", ErrInvalidTarget, f.name, f.typ)
+		}
+		fs.Var(value, name, "overrides "+f.name)
+	}
+	return nil
+}
+
+// LoadServiceWithFlagOverrides loads the service with the given name into target like LoadService,
+// then applies the flags registered using BindFlags which were set on the command line,
+// so flags take precedence over the binding. fs must have been parsed.
+// It returns ErrInvalidTarget if a set flag is bound to a field of an embedded struct pointer which is still nil.
+func (e *Env) LoadServiceWithFlagOverrides(fs *flag.FlagSet, target any, name string) error {
+	fields, rv, err := flagFields(target)
+	if err != nil {
+		return err
+	}
+	if err := e.LoadService(target, name); err != nil {
+		return err
+	}
+	fs.Visit(func(fl *flag.Flag) {
+		value, ok := fl.Value.(*flagValue)
+		f, bound := fields[fl.Name]
+		if !ok || !bound || err != nil {
+			return
+		}
+		field, fieldErr := rv.FieldByIndexErr(f.index)
+		if fieldErr != nil {
+			err = fmt.Errorf("%s: %w: field of nil embedded struct", f.name, ErrInvalidTarget)
+			return
+		}
+		var parsed reflect.Value
+		if parsed, err = value.parsed(); err == nil {
+			field.Set(parsed)
+		}
+	})
+	return err
+}
+
+// flagFields returns the fields of the struct target points to which are tagged with the flag option,
+// keyed by their flag names.
+func flagFields(target any) (map[string]structField, reflect.Value, error) {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, reflect.Value{}, fmt.Errorf("%w: %T is not a pointer to a struct", ErrInvalidTarget, target)
+	}
+	fields := make(map[string]structField)
+	for _, f := range structFields(rv.Elem().Type()) {
+		if !f.opts.has("flag") {
+			continue
+		}
+		name := f.opts["flag"]
+		if name == "" {
+			name = f.name
+		}
+		fields[name] = f
+	}
+	return fields, rv.Elem(), nil
+}
+
+// flagValue is a flag.Value holding the unparsed value of a bound field.
+type flagValue struct {
+	typ   reflect.Type
+	def   string
+	value string
+	set   bool
+}
+
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+	if v.set {
+		return v.value
+	}
+	return v.def
+}
+
+func (v *flagValue) Set(s string) error {
+	if err := v.parse(s); err != nil {
+		return err
+	}
+	v.value, v.set = s, true
+	return nil
+}
+
+// IsBoolFlag allows boolean flags to be set without a value, like flag.Bool.
+func (v *flagValue) IsBoolFlag() bool {
+	return v.typ.Kind() == reflect.Bool
+}
+
+// parsed returns the value of the flag converted to the type of the field.
+func (v *flagValue) parsed() (reflect.Value, error) {
+	result := reflect.New(v.typ).Elem()
+	return result, setFromString(result, v.String())
+}
+
+// parse checks that s can be converted to the type of the field.
+func (v *flagValue) parse(s string) error {
+	return setFromString(reflect.New(v.typ).Elem(), s)
+}
+
+// setFromString parses s according to the type of v and sets v to the result.
+func setFromString(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package xsenv
+
+import (
+	"flag"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testFlagConfig struct {
+	Host    string        `json:"host" xsenv:"flag"`
+	Port    int           `json:"port" xsenv:"flag:db-port"`
+	TLS     bool          `json:"tls" xsenv:"flag"`
+	Timeout time.Duration `json:"timeout" xsenv:"flag"`
+	User    string        `json:"user"`
+}
+
+func newTestFlagSet(t *testing.T, target any) *flag.FlagSet {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	assert.NoError(t, BindFlags(fs, target))
+	return fs
+}
+
+func TestBindFlags(t *testing.T) {
+	config := testFlagConfig{Port: 30015, Timeout: time.Second}
+	fs := newTestFlagSet(t, &config)
+	assert.NotNil(t, fs.Lookup("host"))
+	assert.NotNil(t, fs.Lookup("db-port"))
+	assert.NotNil(t, fs.Lookup("tls"))
+	assert.Nil(t, fs.Lookup("user"))
+	assert.Equal(t, "30015", fs.Lookup("db-port").DefValue)
+	assert.Equal(t, "1s", fs.Lookup("timeout").DefValue)
+
+	// values are validated while parsing
+	assert.Error(t, fs.Parse([]string{"-db-port", "abc"}))
+
+	assert.ErrorIs(t, BindFlags(fs, config), ErrInvalidTarget)
+	assert.ErrorIs(t, BindFlags(fs, &struct {
+		Hosts []string `json:"hosts" xsenv:"flag"`
+	}{}), ErrInvalidTarget)
+}
+
+func TestLoadServiceWithFlagOverrides(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"hana": [{"name": "db", "credentials": {
+		"host": "db.example.com", "port": 30015, "tls": false, "timeout": 5000000000, "user": "app"
+	}}]}}`), FileSource)
+	assert.NoError(t, err)
+
+	var config testFlagConfig
+	fs := newTestFlagSet(t, &config)
+	assert.NoError(t, fs.Parse([]string{"-db-port", "30041", "-tls"}))
+	assert.NoError(t, env.LoadServiceWithFlagOverrides(fs, &config, "db"))
+	assert.Equal(t, testFlagConfig{
+		Host:    "db.example.com",
+		Port:    30041,
+		TLS:     true,
+		Timeout: 5 * time.Second,
+		User:    "app",
+	}, config)
+
+	// flags set to their zero value still override the binding
+	config = testFlagConfig{}
+	fs = newTestFlagSet(t, &config)
+	assert.NoError(t, fs.Parse([]string{"-host="}))
+	assert.NoError(t, env.LoadServiceWithFlagOverrides(fs, &config, "db"))
+	assert.Equal(t, "", config.Host)
+	assert.Equal(t, 30015, config.Port)
+
+	assert.ErrorIs(t, env.LoadServiceWithFlagOverrides(fs, &config, "missing"), ErrServiceNotFound)
+}
+
+func TestFlagsEmbeddedPointer(t *testing.T) {
+	type Inner struct {
+		Region string `json:"region" xsenv:"flag"`
+	}
+	type config struct {
+		*Inner
+		Host string `json:"host"`
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	assert.ErrorIs(t, BindFlags(fs, &config{}), ErrInvalidTarget)
+	assert.NoError(t, BindFlags(fs, &config{Inner: &Inner{}}))
+	assert.NoError(t, fs.Parse([]string{"-region", "eu10"}))
+
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"hana": [
+		{"name": "db", "credentials": {"host": "db.example.com"}},
+		{"name": "regional", "credentials": {"region": "us10"}}
+	]}}`), FileSource)
+	assert.NoError(t, err)
+	var target config
+	assert.ErrorIs(t, env.LoadServiceWithFlagOverrides(fs, &target, "db"), ErrInvalidTarget)
+
+	// embedded pointers allocated while decoding can be overridden
+	target = config{}
+	assert.NoError(t, env.LoadServiceWithFlagOverrides(fs, &target, "regional"))
+	assert.Equal(t, "eu10", target.Region)
+}
@@ -0,0 +1,115 @@
+package xsenv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FlexInt is an int decoding from both JSON numbers and numeric strings,
+// as bindings frequently deliver fields like ports as strings ("port": "443"):
+//
+//	type Config struct {
+//		Host string       `json:"host"`
+//		Port xsenv.FlexInt `json:"port"`
+//	}
+type FlexInt int
+
+// UnmarshalJSON decodes a JSON number or a string holding a decimal integer. null leaves i unchanged.
+func (i *FlexInt) UnmarshalJSON(data []byte) error {
+	s, err := flexString(data)
+	if err != nil || s == nil {
+		return err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(*s))
+	if err != nil {
+		return fmt.Errorf("xsenv: cannot decode %s into FlexInt: %w", data, err)
+	}
+	*i = FlexInt(n)
+	return nil
+}
+
+// FlexBool is a bool decoding from both JSON booleans and strings like "true", "false", "1" or "0".
+type FlexBool bool
+
+// UnmarshalJSON decodes a JSON boolean or a string accepted by strconv.ParseBool. null leaves b unchanged.
+func (b *FlexBool) UnmarshalJSON(data []byte) error {
+	s, err := flexString(data)
+	if err != nil || s == nil {
+		return err
+	}
+	v, err := strconv.ParseBool(strings.TrimSpace(*s))
+	if err != nil {
+		return fmt.Errorf("xsenv: cannot decode %s into FlexBool: %w", data, err)
+	}
+	*b = FlexBool(v)
+	return nil
+}
+
+// TimeLayouts are the layouts FlexTime attempts in order when decoding strings. By default, these are
+// time.RFC3339Nano (which also accepts time.RFC3339), time.RFC1123Z, time.RFC1123, time.DateTime,
+// "2006-01-02T15:04:05" and time.DateOnly. Layouts without a time zone are interpreted as UTC.
+// Change it during initialization to support other formats.
+var TimeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC1123Z,
+	time.RFC1123,
+	time.DateTime,
+	"2006-01-02T15:04:05",
+	time.DateOnly,
+}
+
+// FlexTime is a time.Time decoding from strings in any of the TimeLayouts and from JSON numbers holding
+// Unix timestamps in seconds, for bindings carrying e.g. expiry timestamps in various formats:
+//
+//	type Config struct {
+//		ExpiresAt xsenv.FlexTime `json:"expires_at"`
+//	}
+type FlexTime struct {
+	time.Time
+}
+
+// UnmarshalJSON decodes a string in one of the TimeLayouts or a number of seconds since the Unix epoch.
+// null leaves t unchanged.
+func (t *FlexTime) UnmarshalJSON(data []byte) error {
+	s, err := flexString(data)
+	if err != nil || s == nil {
+		return err
+	}
+	value := strings.TrimSpace(*s)
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("xsenv: cannot decode %s into FlexTime: %w", data, err)
+		}
+		whole := int64(seconds)
+		t.Time = time.Unix(whole, int64((seconds-float64(whole))*1e9)).UTC()
+		return nil
+	}
+	for _, layout := range TimeLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("xsenv: cannot decode %s into FlexTime: no matching layout", data)
+}
+
+// flexString returns the contents of a JSON string or the literal of any other JSON value.
+// It returns nil for null.
+func flexString(data []byte) (*string, error) {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		return nil, nil
+	}
+	s := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, err
+		}
+	}
+	return &s, nil
+}
@@ -0,0 +1,110 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlexInt(t *testing.T) {
+	var config struct {
+		Port  FlexInt  `json:"port"`
+		Other FlexInt  `json:"other"`
+		Unset FlexInt  `json:"unset"`
+		Ptr   *FlexInt `json:"ptr"`
+	}
+	config.Unset = 7
+	assert.NoError(t, json.Unmarshal([]byte(`{"port": "443", "other": 30015, "unset": null, "ptr": " 8 "}`), &config))
+	assert.Equal(t, FlexInt(443), config.Port)
+	assert.Equal(t, FlexInt(30015), config.Other)
+	assert.Equal(t, FlexInt(7), config.Unset)
+	assert.Equal(t, FlexInt(8), *config.Ptr)
+
+	for _, input := range []string{`"abc"`, `""`, `1.5`, `true`, `{}`} {
+		var i FlexInt
+		assert.Error(t, json.Unmarshal([]byte(input), &i), input)
+	}
+}
+
+func TestFlexBool(t *testing.T) {
+	testCases := map[string]FlexBool{
+		`true`:    true,
+		`false`:   false,
+		`"true"`:  true,
+		`"FALSE"`: false,
+		`"1"`:     true,
+		`"0"`:     false,
+	}
+	for input, expected := range testCases {
+		var b FlexBool
+		assert.NoError(t, json.Unmarshal([]byte(input), &b), input)
+		assert.Equal(t, expected, b, input)
+	}
+
+	for _, input := range []string{`"yes"`, `2`, `[]`} {
+		var b FlexBool
+		assert.Error(t, json.Unmarshal([]byte(input), &b), input)
+	}
+
+	// usable through the credentials extraction
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"a": [{"name": "db", "credentials": {"port": "5432", "tls": "true"}}]}}`), RawSource)
+	assert.NoError(t, err)
+	type config struct {
+		Port FlexInt  `json:"port"`
+		TLS  FlexBool `json:"tls"`
+	}
+	cfg, err := Load[config](env, "db")
+	assert.NoError(t, err)
+	assert.Equal(t, config{Port: 5432, TLS: true}, cfg)
+}
+
+func TestFlexTime(t *testing.T) {
+	var config struct {
+		RFC3339  FlexTime  `json:"rfc3339"`
+		DateTime FlexTime  `json:"datetime"`
+		Date     FlexTime  `json:"date"`
+		RFC1123  FlexTime  `json:"rfc1123"`
+		Unix     FlexTime  `json:"unix"`
+		Fraction FlexTime  `json:"fraction"`
+		Unset    FlexTime  `json:"unset"`
+		Ptr      *FlexTime `json:"ptr"`
+	}
+	unset := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	config.Unset = FlexTime{unset}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"rfc3339": "2026-10-14T12:30:00+02:00",
+		"datetime": "2026-10-14 12:30:00",
+		"date": "2026-10-14",
+		"rfc1123": "Wed, 14 Oct 2026 12:30:00 GMT",
+		"unix": 1791980000,
+		"fraction": 1791980000.5,
+		"unset": null,
+		"ptr": "2026-10-14T12:30:00"
+	}`), &config))
+
+	expected := time.Date(2026, 10, 14, 12, 30, 0, 0, time.UTC)
+	assert.True(t, config.RFC3339.Equal(expected.Add(-2*time.Hour)))
+	assert.Equal(t, expected, config.DateTime.Time)
+	assert.Equal(t, time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC), config.Date.Time)
+	assert.True(t, config.RFC1123.Equal(expected))
+	assert.Equal(t, time.Unix(1791980000, 0).UTC(), config.Unix.Time)
+	assert.Equal(t, time.Unix(1791980000, 5e8).UTC(), config.Fraction.Time)
+	assert.Equal(t, unset, config.Unset.Time)
+	assert.Equal(t, expected, config.Ptr.Time)
+
+	for _, input := range []string{`"14.10.2026"`, `""`, `true`, `{}`} {
+		var ft FlexTime
+		assert.Error(t, json.Unmarshal([]byte(input), &ft), input)
+	}
+}
+
+func TestTimeLayouts(t *testing.T) {
+	defer func(layouts []string) { TimeLayouts = layouts }(TimeLayouts)
+	TimeLayouts = append(TimeLayouts, "02.01.2006")
+
+	var ft FlexTime
+	assert.NoError(t, json.Unmarshal([]byte(`"14.10.2026"`), &ft))
+	assert.Equal(t, time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC), ft.Time)
+}
@@ -0,0 +1,40 @@
+package xsenv
+
+import "sync"
+
+// globalState holds the result of loading the process-wide configuration once.
+type globalState struct {
+	once sync.Once
+	env  *Env
+	err  error
+}
+
+var (
+	globalMu sync.Mutex
+	global   *globalState
+)
+
+// Global returns the process-wide configuration, loaded using LoadEnv on the first call.
+// Subsequent calls return the same Env, or the same error if the first load failed,
+// so every part of an application sees a consistent configuration. It is safe for concurrent use.
+func Global() (*Env, error) {
+	globalMu.Lock()
+	if global == nil {
+		global = &globalState{}
+	}
+	state := global
+	globalMu.Unlock()
+
+	state.once.Do(func() {
+		state.env, state.err = LoadEnv()
+	})
+	return state.env, state.err
+}
+
+// ResetGlobal discards the configuration loaded by Global, so the next call loads it again.
+// It is intended for tests.
+func ResetGlobal() {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	global = nil
+}
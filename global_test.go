@@ -0,0 +1,54 @@
+package xsenv
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlobal(t *testing.T) {
+	defer ResetGlobal()
+	ResetGlobal()
+	t.Setenv(EnvironmentKey, `{"VCAP_SERVICES": {"xsuaa": [{"name": "uaa"}]}}`)
+
+	var wg sync.WaitGroup
+	envs := make([]*Env, 8)
+	for i := range envs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			env, err := Global()
+			assert.NoError(t, err)
+			envs[i] = env
+		}()
+	}
+	wg.Wait()
+	for _, env := range envs {
+		assert.Same(t, envs[0], env)
+	}
+
+	// changes are not picked up until the global configuration is reset
+	t.Setenv(EnvironmentKey, `{"VCAP_SERVICES": {"hana": [{"name": "db"}]}}`)
+	env, err := Global()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"uaa"}, env.Names())
+
+	ResetGlobal()
+	env, err = Global()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db"}, env.Names())
+}
+
+func TestGlobalCachesError(t *testing.T) {
+	defer ResetGlobal()
+	ResetGlobal()
+	t.Setenv(EnvironmentKey, `{invalid`)
+
+	_, err := Global()
+	assert.Error(t, err)
+
+	t.Setenv(EnvironmentKey, `{}`)
+	_, second := Global()
+	assert.Equal(t, err, second)
+}
@@ -0,0 +1,55 @@
+package xsenv
+
+// InstanceGUID returns the instance_guid of the service with the given name, e.g. to correlate logs
+// with the service instance. It returns ErrServiceNotFound if the service does not exist
+// and ErrFieldMissing if it has no instance_guid.
+func (e *Env) InstanceGUID(name string) (string, error) {
+	key, ok := e.resolve(name)
+	if !ok {
+		return "", ErrServiceNotFound
+	}
+	if guid := e.metaOf(key).InstanceGUID; guid != "" {
+		return guid, nil
+	}
+	return "", MissingFieldError("instance_guid")
+}
+
+// InstanceName returns the instance_name of the service with the given name.
+// It returns ErrServiceNotFound if the service does not exist and ErrFieldMissing if it has no instance_name.
+func (e *Env) InstanceName(name string) (string, error) {
+	key, ok := e.resolve(name)
+	if !ok {
+		return "", ErrServiceNotFound
+	}
+	if instanceName := e.metaOf(key).InstanceName; instanceName != "" {
+		return instanceName, nil
+	}
+	return "", MissingFieldError("instance_name")
+}
+
+// resolve returns the key in ServicesByName of the service a caller refers to by name.
+// Names are looked up first; if no service has the name, aliases set using WithAliases are consulted,
+// then the service with a matching instance_name is used, since some platforms refer to services
+// by their instance name. Use lookup to work with names only, e.g. when checking for duplicates.
+func (e *Env) resolve(name string) (string, bool) {
+	if key, ok := e.lookup(name); ok {
+		return key, true
+	}
+	normalized := e.normalize(name)
+	if e.opts != nil {
+		for alias, target := range e.opts.aliases {
+			if e.normalize(alias) != normalized {
+				continue
+			}
+			if key, ok := e.lookup(target); ok {
+				return key, true
+			}
+		}
+	}
+	for _, key := range e.sortedKeys() {
+		if instanceName := e.metaOf(key).InstanceName; instanceName != "" && e.normalize(instanceName) == normalized {
+			return key, true
+		}
+	}
+	return "", false
+}
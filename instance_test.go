@@ -0,0 +1,58 @@
+package xsenv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testInstanceEnv = `{"VCAP_SERVICES": {
+	"xsuaa": [{
+		"name": "uaa", "instance_name": "portal-uaa-prod", "instance_guid": "4c9b3f2a-1d2e-4f5a-8b6c-7d8e9f0a1b2c",
+		"credentials": {"clientid": "client"}
+	}],
+	"hana": [{"name": "db", "credentials": {"host": "db.example.com"}}]
+}}`
+
+func TestInstanceGUID(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(testInstanceEnv), FileSource)
+	assert.NoError(t, err)
+
+	guid, err := env.InstanceGUID("uaa")
+	assert.NoError(t, err)
+	assert.Equal(t, "4c9b3f2a-1d2e-4f5a-8b6c-7d8e9f0a1b2c", guid)
+	instanceName, err := env.InstanceName("UAA")
+	assert.NoError(t, err)
+	assert.Equal(t, "portal-uaa-prod", instanceName)
+
+	_, err = env.InstanceGUID("db")
+	assert.ErrorIs(t, err, ErrFieldMissing)
+	_, err = env.InstanceName("db")
+	assert.ErrorIs(t, err, ErrFieldMissing)
+	_, err = env.InstanceGUID("missing")
+	assert.ErrorIs(t, err, ErrServiceNotFound)
+
+	view, ok := env.Find(ByName("uaa"))
+	assert.True(t, ok)
+	assert.Equal(t, "portal-uaa-prod", view.InstanceName)
+	assert.Equal(t, "4c9b3f2a-1d2e-4f5a-8b6c-7d8e9f0a1b2c", view.InstanceGUID)
+}
+
+func TestLookupByInstanceName(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(testInstanceEnv), FileSource)
+	assert.NoError(t, err)
+
+	var config struct {
+		ClientID string `json:"clientid"`
+	}
+	assert.NoError(t, env.LoadService(&config, "Portal-UAA-Prod"))
+	assert.Equal(t, "client", config.ClientID)
+	_, ok := env.Raw("portal-uaa-prod")
+	assert.True(t, ok)
+
+	// names take precedence over instance names
+	assert.NoError(t, env.Set("portal-uaa-prod", map[string]string{"clientid": "other"}))
+	assert.NoError(t, env.LoadService(&config, "portal-uaa-prod"))
+	assert.Equal(t, "other", config.ClientID)
+	assert.Equal(t, 3, env.Len())
+}
@@ -0,0 +1,175 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrLabelMismatch indicates that a service does not carry the expected label.
+	ErrLabelMismatch = errors.New("unexpected service label")
+	// ErrAmbiguousService indicates that more than one service matches where exactly one was expected.
+	ErrAmbiguousService = errors.New("ambiguous service")
+)
+
+// ServiceNotFoundError is returned by lookups searching services by a criterion other than their name,
+// e.g. their label. It matches ErrServiceNotFound using errors.Is.
+type ServiceNotFoundError struct {
+	// Criterion describes what was searched for, e.g. "label=xsuaa".
+	Criterion string
+}
+
+func (e *ServiceNotFoundError) Error() string {
+	return ErrServiceNotFound.Error() + ": " + e.Criterion
+}
+
+// Is reports whether target is ErrServiceNotFound.
+func (e *ServiceNotFoundError) Is(target error) bool {
+	return target == ErrServiceNotFound
+}
+
+// AssertLabel checks that the service with the given name carries the expected label,
+// e.g. that the binding named "portal-uaa" actually is an "xsuaa" service.
+// It returns ErrServiceNotFound if the service does not exist and ErrLabelMismatch
+// if its label differs or is missing.
+func (e *Env) AssertLabel(name, expectedLabel string) error {
+	key, ok := e.resolve(name)
+	if !ok {
+		return ErrServiceNotFound
+	}
+	label := e.metaOf(key).Label
+	if label == "" {
+		return fmt.Errorf("%w: service %s has no label, expected %q", ErrLabelMismatch, name, expectedLabel)
+	}
+	if label != expectedLabel {
+		return fmt.Errorf("%w: service %s has label %q, expected %q", ErrLabelMismatch, name, label, expectedLabel)
+	}
+	return nil
+}
+
+// RequireLabeled checks that every service named like a key of spec exists and carries the label it maps to,
+// e.g. {"mydb": "postgresql", "auth": "xsuaa"}, as a single preflight check at startup.
+// The returned error joins one error per missing service, matching ErrServiceNotFound, and per mismatching
+// label, matching ErrLabelMismatch, in the order of the service names. It returns nil if all services match.
+func (e *Env) RequireLabeled(spec map[string]string) error {
+	names := make([]string, 0, len(spec))
+	for name := range spec {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		err := e.AssertLabel(name, spec[name])
+		if errors.Is(err, ErrServiceNotFound) {
+			err = fmt.Errorf("%w: %s", err, name)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FindByAllTags returns the sorted names of all services carrying every one of the given tags.
+func (e *Env) FindByAllTags(tags ...string) []string {
+	var names []string
+	for _, key := range e.sortedKeys() {
+		if hasAllTags(e.metaOf(key).Tags, tags) {
+			names = append(names, key)
+		}
+	}
+	return names
+}
+
+// FindByAnyTag returns the sorted names of all services carrying at least one of the given tags,
+// e.g. "mysql", "postgres" and "relational" to select any relational database.
+func (e *Env) FindByAnyTag(tags ...string) []string {
+	var names []string
+	for _, key := range e.sortedKeys() {
+		if hasAnyTag(e.metaOf(key).Tags, tags) {
+			names = append(names, key)
+		}
+	}
+	return names
+}
+
+// AllTags returns the sorted set of all tags carried by any service, e.g. to show which kinds of services are bound.
+func (e *Env) AllTags() []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for key := range e.ServicesByName {
+		for _, tag := range e.metaOf(key).Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// hasAnyTag reports whether have contains at least one tag of want.
+func hasAnyTag(have, want []string) bool {
+	for _, tag := range want {
+		if slices.Contains(have, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAllTags reports whether have contains every tag of want.
+func hasAllTags(have, want []string) bool {
+	for _, tag := range want {
+		if !slices.Contains(have, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadSingleByLabel loads the only service carrying the given label into target,
+// e.g. the single "xsuaa" binding of an application.
+// It returns a *ServiceNotFoundError if no service carries the label and ErrAmbiguousService
+// if more than one does, so the single-instance assumption fails loudly.
+func (e *Env) LoadSingleByLabel(target UnmarshalService, label string) error {
+	names := e.namesByLabel(label)
+	switch len(names) {
+	case 0:
+		return &ServiceNotFoundError{Criterion: "label=" + label}
+	case 1:
+		return e.decode(target, names[0])
+	default:
+		return fmt.Errorf("%w: label %q is carried by %s", ErrAmbiguousService, label, strings.Join(names, ", "))
+	}
+}
+
+// ServicesByLabel returns the raw configurations of all services grouped by their label, e.g. all "hana" bindings,
+// each group ordered by the names of the services. Services without a label are left out.
+// A new map is returned on every call, so callers may modify it.
+func (e *Env) ServicesByLabel() map[string][]*json.RawMessage {
+	result := make(map[string][]*json.RawMessage)
+	for _, key := range e.sortedKeys() {
+		if label := e.metaOf(key).Label; label != "" {
+			result[label] = append(result[label], e.ServicesByName[key])
+		}
+	}
+	return result
+}
+
+// namesByLabel returns the sorted names of all services carrying the given label.
+func (e *Env) namesByLabel(label string) []string {
+	var names []string
+	for _, key := range e.sortedKeys() {
+		if e.metaOf(key).Label == label {
+			names = append(names, key)
+		}
+	}
+	return names
+}
@@ -0,0 +1,138 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testLabelEnv = `{"VCAP_SERVICES": {
+	"xsuaa": [{"name": "portal-uaa", "label": "xsuaa"}],
+	"hana": [{"name": "portal-db", "label": "hana"}],
+	"user-provided": [{"name": "custom"}]
+}}`
+
+func TestAssertLabel(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(testLabelEnv), RawSource)
+	assert.NoError(t, err)
+
+	assert.NoError(t, env.AssertLabel("Portal-UAA", "xsuaa"))
+
+	err = env.AssertLabel("portal-db", "xsuaa")
+	assert.ErrorIs(t, err, ErrLabelMismatch)
+	assert.ErrorContains(t, err, `has label "hana", expected "xsuaa"`)
+
+	err = env.AssertLabel("custom", "xsuaa")
+	assert.ErrorIs(t, err, ErrLabelMismatch)
+	assert.ErrorContains(t, err, "has no label")
+
+	assert.ErrorIs(t, env.AssertLabel("nonexistent", "xsuaa"), ErrServiceNotFound)
+}
+
+func TestFindByAllTags(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {
+		"postgresql-db": [
+			{"name": "pg-b", "tags": ["database", "relational", "postgres"]},
+			{"name": "pg-a", "tags": ["relational", "database"]}
+		],
+		"redis": [{"name": "cache", "tags": ["database", "cache"]}],
+		"user-provided": [{"name": "untagged"}]
+	}}`), RawSource)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"pg-a", "pg-b"}, env.FindByAllTags("database", "relational"))
+	assert.Equal(t, []string{"cache", "pg-a", "pg-b"}, env.FindByAllTags("database"))
+	assert.Equal(t, []string{"pg-b"}, env.FindByAllTags("postgres", "database"))
+	assert.Empty(t, env.FindByAllTags("database", "mysql"))
+	assert.Len(t, env.FindByAllTags(), 4)
+}
+
+func TestFindByAnyTag(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {
+		"postgresql-db": [{"name": "pg", "tags": ["database", "relational", "postgres"]}],
+		"mysql": [{"name": "my", "tags": ["mysql"]}],
+		"redis": [{"name": "cache", "tags": ["database", "cache"]}],
+		"user-provided": [{"name": "untagged"}]
+	}}`), RawSource)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"my", "pg"}, env.FindByAnyTag("mysql", "postgres", "relational"))
+	assert.Equal(t, []string{"cache", "pg"}, env.FindByAnyTag("database"))
+	assert.Empty(t, env.FindByAnyTag("mongodb"))
+	assert.Empty(t, env.FindByAnyTag())
+}
+
+func TestLoadSingleByLabel(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {
+		"xsuaa": [{"name": "uaa", "label": "xsuaa"}],
+		"hana": [{"name": "db-1", "label": "hana"}, {"name": "db-2", "label": "hana"}]
+	}}`), RawSource)
+	assert.NoError(t, err)
+
+	var named testNamedService
+	assert.NoError(t, env.LoadSingleByLabel(&named, "xsuaa"))
+	assert.Equal(t, "uaa", named.Name)
+
+	err = env.LoadSingleByLabel(&named, "hana")
+	assert.ErrorIs(t, err, ErrAmbiguousService)
+	assert.ErrorContains(t, err, "db-1, db-2")
+
+	err = env.LoadSingleByLabel(&named, "redis")
+	assert.ErrorIs(t, err, ErrServiceNotFound)
+	assert.NotErrorIs(t, err, ErrAmbiguousService)
+	assert.EqualError(t, err, "service not found: label=redis")
+	var notFound *ServiceNotFoundError
+	assert.ErrorAs(t, err, &notFound)
+	assert.Equal(t, "label=redis", notFound.Criterion)
+}
+
+func TestServicesByLabel(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {
+		"xsuaa": [{"name": "uaa", "label": "xsuaa"}],
+		"hana": [{"name": "db-2", "label": "hana"}, {"name": "db-1", "label": "hana"}],
+		"user-provided": [{"name": "plain"}]
+	}}`), RawSource)
+	assert.NoError(t, err)
+
+	byLabel := env.ServicesByLabel()
+	assert.Len(t, byLabel, 2)
+	assert.Equal(t, []*json.RawMessage{env.ServicesByName["uaa"]}, byLabel["xsuaa"])
+	assert.Equal(t, []*json.RawMessage{env.ServicesByName["db-1"], env.ServicesByName["db-2"]}, byLabel["hana"])
+
+	// every call returns a new map
+	delete(byLabel, "xsuaa")
+	assert.Len(t, env.ServicesByLabel(), 2)
+	assert.Empty(t, (&Env{}).ServicesByLabel())
+}
+
+func TestRequireLabeled(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(testLabelEnv), RawSource, WithAliases(map[string]string{
+		"auth": "portal-uaa",
+		"mydb": "portal-db",
+	}))
+	assert.NoError(t, err)
+	assert.NoError(t, env.RequireLabeled(map[string]string{"auth": "xsuaa"}))
+	assert.NoError(t, env.RequireLabeled(nil))
+
+	err = env.RequireLabeled(map[string]string{
+		"auth":    "xsuaa",
+		"mydb":    "postgresql",
+		"missing": "hana",
+	})
+	assert.ErrorIs(t, err, ErrServiceNotFound)
+	assert.ErrorIs(t, err, ErrLabelMismatch)
+	assert.EqualError(t, err, "service not found: missing\n"+
+		`unexpected service label: service mydb has label "hana", expected "postgresql"`)
+}
+
+func TestAllTags(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {
+		"postgresql-db": [{"name": "pg", "tags": ["relational", "database", "postgres"]}],
+		"redis": [{"name": "cache", "tags": ["database", "cache"]}],
+		"user-provided": [{"name": "untagged"}]
+	}}`), RawSource)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cache", "database", "postgres", "relational"}, env.AllTags())
+	assert.Empty(t, (&Env{}).AllTags())
+}
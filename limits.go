@@ -0,0 +1,142 @@
+package xsenv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var (
+	// ErrConfigTooLarge indicates that the configuration exceeds the size set using WithMaxSize.
+	ErrConfigTooLarge = errors.New("configuration too large")
+	// ErrConfigTooDeep indicates that the configuration exceeds the nesting depth set using WithMaxDepth.
+	ErrConfigTooDeep = errors.New("configuration nested too deeply")
+)
+
+// WithMaxSize makes loading fail with ErrConfigTooLarge if the configuration is larger than n bytes,
+// protecting against memory exhaustion when loading from untrusted readers.
+// Readers and files are never read beyond the limit. Decompressed data is limited as well, see WithGzip.
+// It defaults to 0 (unlimited).
+func WithMaxSize(n int64) Option {
+	return func(o *options) {
+		o.maxSize = n
+	}
+}
+
+// WithMaxDepth makes loading fail with ErrConfigTooDeep if objects and arrays are nested deeper than n levels
+// anywhere in the configuration, counting the top-level value as the first level, so services of the usual
+// {"VCAP_SERVICES": {"group": [...]}} shape are on the fourth level. The depth is checked before the configuration
+// is decoded, protecting the decoder against deeply nested input. It defaults to 0 (unlimited).
+func WithMaxDepth(n int) Option {
+	return func(o *options) {
+		o.maxDepth = n
+	}
+}
+
+// limit wraps r to fail with ErrConfigTooLarge once more than the size set using WithMaxSize is read.
+func (o *options) limit(r io.Reader) io.Reader {
+	if o.maxSize <= 0 {
+		return r
+	}
+	return &limitedReader{r: io.LimitReader(r, o.maxSize+1), max: o.maxSize}
+}
+
+// readFile reads the file name like os.ReadFile, but fails with ErrConfigTooLarge once more than the size
+// set using WithMaxSize is read, so large files are not buffered.
+func (o *options) readFile(name string) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(o.limit(f))
+}
+
+// checkSize returns ErrConfigTooLarge if data exceeds the size set using WithMaxSize.
+func (o *options) checkSize(data []byte) error {
+	if o.maxSize > 0 && int64(len(data)) > o.maxSize {
+		return fmt.Errorf("%w: exceeds %d bytes", ErrConfigTooLarge, o.maxSize)
+	}
+	return nil
+}
+
+// checkDepth returns ErrConfigTooDeep if data exceeds the depth set using WithMaxDepth.
+// Invalid JSON is left to be reported by the decoder.
+func (o *options) checkDepth(data []byte) error {
+	if o.maxDepth <= 0 {
+		return nil
+	}
+	return (&depthScanner{max: o.maxDepth}).scan(data)
+}
+
+// limitDepth wraps r to fail with ErrConfigTooDeep once the data read exceeds the depth set using WithMaxDepth.
+func (o *options) limitDepth(r io.Reader) io.Reader {
+	if o.maxDepth <= 0 {
+		return r
+	}
+	return &depthReader{r: r, scanner: depthScanner{max: o.maxDepth}}
+}
+
+// depthScanner tracks the nesting depth of JSON data passed to scan in one or more chunks.
+type depthScanner struct {
+	max      int
+	depth    int
+	inString bool
+	escaped  bool
+}
+
+// scan advances the scanner over data, returning ErrConfigTooDeep once the depth exceeds max.
+func (s *depthScanner) scan(data []byte) error {
+	for _, c := range data {
+		switch {
+		case s.escaped:
+			s.escaped = false
+		case s.inString:
+			switch c {
+			case '\\':
+				s.escaped = true
+			case '"':
+				s.inString = false
+			}
+		case c == '"':
+			s.inString = true
+		case c == '{' || c == '[':
+			if s.depth++; s.depth > s.max {
+				return fmt.Errorf("%w: exceeds %d levels", ErrConfigTooDeep, s.max)
+			}
+		case c == '}' || c == ']':
+			s.depth--
+		}
+	}
+	return nil
+}
+
+// depthReader fails with ErrConfigTooDeep once the data read from r exceeds the depth of scanner.
+type depthReader struct {
+	r       io.Reader
+	scanner depthScanner
+}
+
+func (d *depthReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	if scanErr := d.scanner.scan(p[:n]); scanErr != nil {
+		return 0, scanErr
+	}
+	return n, err
+}
+
+// limitedReader fails with ErrConfigTooLarge once more than max bytes were read from r.
+type limitedReader struct {
+	r    io.Reader
+	max  int64
+	read int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	if l.read += int64(n); l.read > l.max {
+		return 0, fmt.Errorf("%w: exceeds %d bytes", ErrConfigTooLarge, l.max)
+	}
+	return n, err
+}
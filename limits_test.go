@@ -0,0 +1,114 @@
+package xsenv
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithMaxSize(t *testing.T) {
+	data := []byte(`{"VCAP_SERVICES": {"xsuaa": [{"name": "uaa"}]}}`)
+	size := int64(len(data))
+
+	_, err := LoadEnvFromReader(bytes.NewReader(data), WithMaxSize(size))
+	assert.NoError(t, err)
+	_, err = LoadEnvFromReaderStreaming(bytes.NewReader(data), WithMaxSize(size))
+	assert.NoError(t, err)
+
+	_, err = LoadEnvFromReader(bytes.NewReader(data), WithMaxSize(size-1))
+	assert.ErrorIs(t, err, ErrConfigTooLarge)
+	_, err = LoadEnvFromReaderStreaming(bytes.NewReader(data), WithMaxSize(size-1))
+	assert.ErrorIs(t, err, ErrConfigTooLarge)
+	_, err = loadEnvFromBytes(data, FileSource, WithMaxSize(size-1))
+	assert.ErrorIs(t, err, ErrConfigTooLarge)
+
+	// the reader is not consumed beyond the limit
+	reader := strings.NewReader(strings.Repeat(" ", 1024) + string(data))
+	_, err = LoadEnvFromReader(reader, WithMaxSize(16))
+	assert.ErrorIs(t, err, ErrConfigTooLarge)
+	assert.Equal(t, 1024+len(data)-17, reader.Len())
+
+	// decompressed data is limited as well
+	compressed := gzipData(t, string(data)+strings.Repeat(" ", 4096))
+	_, err = LoadEnvFromReader(bytes.NewReader(compressed), WithGzip(), WithMaxSize(1024))
+	assert.ErrorIs(t, err, ErrConfigTooLarge)
+	_, err = LoadEnvFromReaderStreaming(bytes.NewReader(compressed), WithGzip(), WithMaxSize(1024))
+	assert.ErrorIs(t, err, ErrConfigTooLarge)
+}
+
+func TestWithMaxSizeFiles(t *testing.T) {
+	data := []byte(`{"VCAP_SERVICES": {"xsuaa": [{"name": "uaa"}]}}`)
+	size := int64(len(data))
+	dir := t.TempDir()
+	path := filepath.Join(dir, "default-env.json")
+	assert.NoError(t, os.WriteFile(path, data, 0o600))
+	dotenv := filepath.Join(dir, ".env")
+	assert.NoError(t, os.WriteFile(dotenv, []byte("VCAP_SERVICES='"+string(data)+"'\n"), 0o600))
+
+	_, err := LoadEnvFromFile(path, WithMaxSize(size))
+	assert.NoError(t, err)
+	_, err = LoadEnvFromFile(path, WithMaxSize(size-1))
+	assert.ErrorIs(t, err, ErrConfigTooLarge)
+	_, err = LoadEnvFromDotEnv(dotenv, WithMaxSize(size))
+	assert.ErrorIs(t, err, ErrConfigTooLarge)
+	_, err = LoadEnvFromFileWithRetry(context.Background(), path, 1, 0, WithMaxSize(size-1))
+	assert.ErrorIs(t, err, ErrConfigTooLarge)
+	_, err = LoadEnvFrom(FileLoader{Path: path}, WithMaxSize(size-1))
+	assert.ErrorIs(t, err, ErrConfigTooLarge)
+
+	// readers of loaders are not consumed beyond the limit
+	reader := bytes.NewReader(data)
+	_, err = LoadEnvFrom(ReaderLoader{Reader: reader}, WithMaxSize(16))
+	assert.ErrorIs(t, err, ErrConfigTooLarge)
+	assert.Equal(t, len(data)-17, reader.Len())
+
+	_, err = LoadServicesMap([]byte(`{"xsuaa": [{"name": "uaa"}]}`), WithMaxSize(5))
+	assert.ErrorIs(t, err, ErrConfigTooLarge)
+}
+
+func TestWithMaxDepth(t *testing.T) {
+	nested := `{"a": ` + strings.Repeat("[", 10) + strings.Repeat("]", 10) + `}`
+	data := []byte(`{"VCAP_SERVICES": {"xsuaa": [{"name": "uaa", "credentials": ` + nested + `}]}}`)
+
+	// top-level object, groups object, services array, service object, credentials object, 10 arrays
+	_, err := loadEnvFromBytes(data, RawSource, WithMaxDepth(15))
+	assert.NoError(t, err)
+	_, err = LoadEnvFromReaderStreaming(bytes.NewReader(data), WithMaxDepth(15))
+	assert.NoError(t, err)
+
+	_, err = loadEnvFromBytes(data, RawSource, WithMaxDepth(14))
+	assert.ErrorIs(t, err, ErrConfigTooDeep)
+	_, err = LoadEnvFromReaderStreaming(bytes.NewReader(data), WithMaxDepth(14))
+	assert.ErrorIs(t, err, ErrConfigTooDeep)
+
+	// nesting outside of services counts as well
+	data = []byte(`{"other": ` + strings.Repeat("[", 500) + strings.Repeat("]", 500) + `, "VCAP_SERVICES": {}}`)
+	_, err = loadEnvFromBytes(data, RawSource, WithMaxDepth(3))
+	assert.ErrorIs(t, err, ErrConfigTooDeep)
+	_, err = LoadEnvFromReaderStreaming(bytes.NewReader(data), WithMaxDepth(3))
+	assert.ErrorIs(t, err, ErrConfigTooDeep)
+	_, err = LoadServicesMap([]byte(`{"xsuaa": [{"name": "uaa", "credentials": `+nested+`}]}`), WithMaxDepth(3))
+	assert.ErrorIs(t, err, ErrConfigTooDeep)
+
+	// the depth is checked before decoding
+	data = []byte(`{"other": ` + strings.Repeat("[", 500))
+	_, err = loadEnvFromBytes(data, RawSource, WithMaxDepth(3))
+	assert.ErrorIs(t, err, ErrConfigTooDeep)
+	_, err = LoadEnvFromReaderStreaming(bytes.NewReader(data), WithMaxDepth(3))
+	assert.ErrorIs(t, err, ErrConfigTooDeep)
+
+	// brackets within strings do not count
+	data = []byte(`{"VCAP_SERVICES": {"xsuaa": [{"name": "[[[{{{\"[[["}]}}`)
+	_, err = loadEnvFromBytes(data, RawSource, WithMaxDepth(4))
+	assert.NoError(t, err)
+	_, err = LoadEnvFromReaderStreaming(bytes.NewReader(data), WithMaxDepth(4))
+	assert.NoError(t, err)
+
+	_, err = loadEnvFromBytes(data, RawSource)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,92 @@
+package xsenv
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// SourceLoader retrieves the raw environment configuration from an arbitrary backend,
+// e.g. Vault, Consul or a secrets manager. Parsing is left to LoadEnvFrom.
+type SourceLoader interface {
+	// Load returns the configuration data and the source it was retrieved from.
+	Load() ([]byte, Source, error)
+}
+
+// LoadEnvFrom loads the environment configuration retrieved by loader.
+// Loaders must report one of the known sources, custom backends like Vault report RawSource.
+// It returns an Env instance on success or an error if loading fails, ErrInvalidSource if the source is unknown.
+func LoadEnvFrom(loader SourceLoader, opts ...Option) (*Env, error) {
+	var (
+		data   []byte
+		source Source
+		err    error
+	)
+	if limited, ok := loader.(limitedLoader); ok {
+		data, source, err = limited.loadLimited(newOptions(opts))
+	} else {
+		data, source, err = loader.Load()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !source.Valid() {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidSource, source)
+	}
+	return loadEnvFromBytes(data, source, opts...)
+}
+
+// limitedLoader is implemented by the loaders of this package which respect WithMaxSize while reading.
+type limitedLoader interface {
+	loadLimited(o *options) ([]byte, Source, error)
+}
+
+// FileLoader loads the configuration from the file at Path.
+type FileLoader struct {
+	Path string
+}
+
+// Load reads the file and reports FileSource.
+func (l FileLoader) Load() ([]byte, Source, error) {
+	return l.loadLimited(newOptions(nil))
+}
+
+func (l FileLoader) loadLimited(o *options) ([]byte, Source, error) {
+	data, err := o.readFile(l.Path)
+	return data, FileSource, err
+}
+
+// EnvironmentLoader loads the configuration from the environment variable Key,
+// defaulting to EnvironmentKey if Key is empty.
+type EnvironmentLoader struct {
+	Key string
+}
+
+// Load reads the environment variable and reports EnvironmentSource.
+// If the variable is not set, the returned error wraps os.ErrNotExist.
+func (l EnvironmentLoader) Load() ([]byte, Source, error) {
+	key := l.Key
+	if key == "" {
+		key = EnvironmentKey
+	}
+	data, ok := os.LookupEnv(key)
+	if !ok {
+		return nil, EnvironmentSource, fmt.Errorf("%s is not set: %w", key, os.ErrNotExist)
+	}
+	return []byte(data), EnvironmentSource, nil
+}
+
+// ReaderLoader loads the configuration from Reader.
+type ReaderLoader struct {
+	Reader io.Reader
+}
+
+// Load reads Reader until EOF and reports RawSource.
+func (l ReaderLoader) Load() ([]byte, Source, error) {
+	return l.loadLimited(newOptions(nil))
+}
+
+func (l ReaderLoader) loadLimited(o *options) ([]byte, Source, error) {
+	data, err := io.ReadAll(o.limit(l.Reader))
+	return data, RawSource, err
+}
@@ -0,0 +1,66 @@
+package xsenv
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testLoaderEnv = `{"VCAP_SERVICES": {"xsuaa": [{"name": "uaa", "credentials": {"clientid": "x"}}]}}`
+
+type failingLoader struct{}
+
+func (failingLoader) Load() ([]byte, Source, error) {
+	return nil, "", errors.New("backend unavailable")
+}
+
+type staticLoader struct {
+	source Source
+}
+
+func (l staticLoader) Load() ([]byte, Source, error) {
+	return []byte(testLoaderEnv), l.source, nil
+}
+
+func TestLoadEnvFrom(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "env.json")
+	assert.NoError(t, os.WriteFile(path, []byte(testLoaderEnv), 0o600))
+	t.Setenv("CUSTOM_VCAP", testLoaderEnv)
+
+	testCases := []struct {
+		name   string
+		loader SourceLoader
+		source Source
+	}{
+		{"file", FileLoader{Path: path}, FileSource},
+		{"environment", EnvironmentLoader{Key: "CUSTOM_VCAP"}, EnvironmentSource},
+		{"reader", ReaderLoader{Reader: strings.NewReader(testLoaderEnv)}, RawSource},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			env, err := LoadEnvFrom(tc.loader)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.source, env.Source)
+			assert.Equal(t, []string{"uaa"}, env.Names())
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := LoadEnvFrom(failingLoader{})
+		assert.EqualError(t, err, "backend unavailable")
+
+		_, err = LoadEnvFrom(EnvironmentLoader{Key: "XSENV_UNSET_VARIABLE"})
+		assert.ErrorIs(t, err, os.ErrNotExist)
+
+		_, err = LoadEnvFrom(FileLoader{Path: filepath.Join(t.TempDir(), "missing.json")})
+		assert.ErrorIs(t, err, os.ErrNotExist)
+
+		_, err = LoadEnvFrom(staticLoader{source: "vault"})
+		assert.ErrorIs(t, err, ErrInvalidSource)
+		assert.EqualError(t, err, `invalid source: "vault"`)
+	})
+}
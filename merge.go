@@ -0,0 +1,140 @@
+package xsenv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// Merge returns a new Env holding the services of both e and other.
+// Services of other take precedence over services of e with the same name.
+// Service groups present in both environments are concatenated rather than overwritten,
+// so every service of a shared group survives the merge.
+// The Source of the result is MergedSource unless both environments share the same source,
+// the source of every single service is kept in ServiceSources.
+func (e *Env) Merge(other *Env) *Env {
+	source := e.Source
+	if other.Source != source {
+		source = MergedSource
+	}
+	merged := newEnv(source, e.opts)
+	merged.mergeFrom(e)
+	merged.mergeFrom(other)
+	merged.skipped = slices.Concat(e.skipped, other.skipped)
+	return merged
+}
+
+// mergeFrom copies all services of src into e, replacing services with the same name.
+func (e *Env) mergeFrom(src *Env) {
+	grouped := make(map[string]bool)
+
+	groups := make([]string, 0, len(src.groups))
+	for group := range src.groups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	for _, group := range groups {
+		for _, key := range src.groups[group] {
+			msg, ok := src.ServicesByName[key]
+			if !ok {
+				continue
+			}
+			grouped[key] = true
+			source, _ := src.SourceOf(key)
+			e.add(group, key, msg, source, src.metaOf(key))
+		}
+	}
+
+	// services which are not part of any group, e.g. when Env was created by hand
+	for key, msg := range src.ServicesByName {
+		if grouped[key] {
+			continue
+		}
+		source, _ := src.SourceOf(key)
+		e.add("", key, msg, source, src.metaOf(key))
+	}
+}
+
+// LoadMerged decodes the combined credentials of the services with the given names into target, for
+// configurations split across several bindings, e.g. connection details in one and TLS certificates in another.
+// Credentials are merged in the given order like a JSON merge patch (RFC 7386): objects are merged recursively,
+// other values of later services replace those of earlier ones and null removes a value.
+// The merged credentials are decoded like a single service using the same rules as Env.LoadService,
+// along with the other fields of the first service, e.g. for implementations of UnmarshalService.
+// Without names, target is left unchanged. It returns ErrServiceNotFound if one of the services does not exist. Errors are prefixed with the name of the service.
+func (e *Env) LoadMerged(target any, names ...string) error {
+	var (
+		merged any
+		first  *json.RawMessage
+	)
+	for _, name := range names {
+		key, ok := e.resolve(name)
+		if !ok {
+			e.metrics().IncNotFound(name)
+			return fmt.Errorf("%s: %w", name, ErrServiceNotFound)
+		}
+		msg, err := e.prepare(key)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if first == nil {
+			first = msg
+		}
+		data, err := decodedData(msg)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var patch any
+		if err := dec.Decode(&patch); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		merged = mergePatch(merged, patch)
+	}
+
+	if first == nil {
+		return nil
+	}
+	// the merged credentials are decoded along with the metadata of the first service
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(*first, &fields); err != nil {
+		return err
+	}
+	if fields == nil {
+		fields = make(map[string]json.RawMessage)
+	}
+	var err error
+	if fields["credentials"], err = json.Marshal(merged); err != nil {
+		return err
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	msg := json.RawMessage(data)
+	return decodeService(target, &msg)
+}
+
+// mergePatch applies patch to target following JSON merge patch semantics and returns the result.
+func mergePatch(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = make(map[string]any)
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergePatch(targetObject[key], value)
+		}
+	}
+	return targetObject
+}
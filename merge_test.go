@@ -0,0 +1,118 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	a, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {
+		"hana": [{"name": "db-a"}, {"name": "shared", "origin": "a"}],
+		"xsuaa": [{"name": "uaa"}]
+	}}`), FileSource)
+	assert.NoError(t, err)
+	b, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {
+		"hana": [{"name": "db-b"}, {"name": "shared", "origin": "b"}]
+	}}`), EnvironmentSource)
+	assert.NoError(t, err)
+
+	merged := a.Merge(b)
+	assert.Equal(t, MergedSource, merged.Source)
+	assert.Len(t, merged.ServicesByName, 4)
+
+	// all services of the shared group survive
+	assert.ElementsMatch(t, []string{"db-a", "db-b", "shared"}, merged.groups["hana"])
+	assert.Equal(t, []string{"uaa"}, merged.groups["xsuaa"])
+
+	// the second environment wins on name collisions
+	assert.JSONEq(t, `{"name": "shared", "origin": "b"}`, string(*merged.ServicesByName["shared"]))
+
+	// the inputs are left untouched
+	assert.Len(t, a.ServicesByName, 3)
+	assert.Len(t, b.ServicesByName, 2)
+}
+
+func TestMergeSameSource(t *testing.T) {
+	a, _ := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"hana": [{"name": "a"}]}}`), FileSource)
+	b, _ := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"hana": [{"name": "b"}]}}`), FileSource)
+	assert.Equal(t, FileSource, a.Merge(b).Source)
+}
+
+func TestMergeUngrouped(t *testing.T) {
+	msg := json.RawMessage(`{"name": "manual"}`)
+	manual := &Env{Source: RawSource, ServicesByName: map[string]*json.RawMessage{"manual": &msg}}
+	a, _ := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"hana": [{"name": "a"}]}}`), RawSource)
+
+	merged := a.Merge(manual)
+	assert.Len(t, merged.ServicesByName, 2)
+	assert.Contains(t, merged.ServicesByName, "manual")
+}
+
+func TestMergeServiceSources(t *testing.T) {
+	file, _ := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"hana": [{"name": "db"}, {"name": "local"}]}}`), FileSource)
+	environment, _ := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"hana": [{"name": "db"}]}}`), EnvironmentSource)
+
+	merged := file.Merge(environment)
+	source, ok := merged.SourceOf("DB")
+	assert.True(t, ok)
+	assert.Equal(t, EnvironmentSource, source)
+
+	source, ok = merged.SourceOf("local")
+	assert.True(t, ok)
+	assert.Equal(t, FileSource, source)
+
+	// provenance survives subsequent merges
+	raw, _ := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"xsuaa": [{"name": "uaa"}]}}`), RawSource)
+	merged = merged.Merge(raw)
+	source, _ = merged.SourceOf("db")
+	assert.Equal(t, EnvironmentSource, source)
+	source, _ = merged.SourceOf("uaa")
+	assert.Equal(t, RawSource, source)
+
+	_, ok = merged.SourceOf("nonexistent")
+	assert.False(t, ok)
+}
+
+func TestLoadMerged(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"user-provided": [
+		{"name": "db", "credentials": {"host": "db.example.com", "port": 5432, "tls": {"enabled": false, "ca": "old"}, "debug": true}},
+		{"name": "db-tls", "credentials": {"tls": {"enabled": true, "cert": "cert"}, "debug": null}},
+		{"name": "broken", "credentials": {"port": "5432"}}
+	]}}`), RawSource)
+	assert.NoError(t, err)
+
+	type tlsConfig struct {
+		Enabled bool   `json:"enabled"`
+		CA      string `json:"ca"`
+		Cert    string `json:"cert"`
+	}
+	type dbConfig struct {
+		Host  string    `json:"host"`
+		Port  int       `json:"port"`
+		TLS   tlsConfig `json:"tls"`
+		Debug bool      `json:"debug"`
+	}
+	var config dbConfig
+	assert.NoError(t, env.LoadMerged(&config, "db", "db-tls"))
+	assert.Equal(t, dbConfig{
+		Host: "db.example.com",
+		Port: 5432,
+		TLS:  tlsConfig{Enabled: true, CA: "old", Cert: "cert"},
+	}, config)
+
+	// later services win
+	var named testNamedService
+	assert.NoError(t, env.LoadMerged(&named, "db-tls", "db"))
+	assert.Equal(t, "db-tls", named.Name)
+	config = dbConfig{}
+	assert.NoError(t, env.LoadMerged(&config, "db-tls", "db"))
+	assert.False(t, config.TLS.Enabled)
+	assert.True(t, config.Debug)
+
+	err = env.LoadMerged(&config, "db", "missing")
+	assert.ErrorIs(t, err, ErrServiceNotFound)
+	assert.EqualError(t, err, "missing: service not found")
+	assert.Error(t, env.LoadMerged(&config, "db", "broken"))
+}
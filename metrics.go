@@ -0,0 +1,38 @@
+package xsenv
+
+// MetricsRecorder receives events for operational metrics, e.g. to back Prometheus counters,
+// without this package depending on a metrics library. See WithMetrics.
+type MetricsRecorder interface {
+	// IncLoad is called whenever a configuration was loaded successfully, once for every source
+	// loaded, e.g. twice when merging the environment variable and the default file using WithMergeSources.
+	IncLoad(source Source)
+	// IncNotFound is called whenever a service looked up by name using Env.LoadService, Load or Get does not exist.
+	IncNotFound(name string)
+	// IncUnmarshalError is called whenever a service fails to decode, with the name of the service.
+	IncUnmarshalError(name string)
+}
+
+// WithMetrics sets the recorder notified about loads, lookups of missing services and decoding failures.
+// By default, nothing is recorded.
+func WithMetrics(m MetricsRecorder) Option {
+	return func(o *options) {
+		if m != nil {
+			o.metrics = m
+		}
+	}
+}
+
+// noopMetrics is the MetricsRecorder used if none was configured.
+type noopMetrics struct{}
+
+func (noopMetrics) IncLoad(Source)           {}
+func (noopMetrics) IncNotFound(string)       {}
+func (noopMetrics) IncUnmarshalError(string) {}
+
+// metrics returns the MetricsRecorder of e.
+func (e *Env) metrics() MetricsRecorder {
+	if e.opts == nil {
+		return noopMetrics{}
+	}
+	return e.opts.metrics
+}
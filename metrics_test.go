@@ -0,0 +1,49 @@
+package xsenv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingMetrics struct {
+	loads           []Source
+	notFound        []string
+	unmarshalErrors []string
+}
+
+func (m *recordingMetrics) IncLoad(source Source)   { m.loads = append(m.loads, source) }
+func (m *recordingMetrics) IncNotFound(name string) { m.notFound = append(m.notFound, name) }
+func (m *recordingMetrics) IncUnmarshalError(name string) {
+	m.unmarshalErrors = append(m.unmarshalErrors, name)
+}
+
+func TestWithMetrics(t *testing.T) {
+	m := &recordingMetrics{}
+	env, err := loadEnvFromBytes([]byte(testGroupEnv), FileSource, WithMetrics(m), WithDecodeCache())
+	assert.NoError(t, err)
+	assert.Equal(t, []Source{FileSource}, m.loads)
+
+	var config testHANAConfig
+	assert.NoError(t, env.LoadService(&config, "db-1"))
+	assert.Error(t, env.LoadService(&config, "broken"))
+	assert.ErrorIs(t, env.LoadService(&config, "missing"), ErrServiceNotFound)
+	_, err = Get[testHANAConfig](env, "also-missing")
+	assert.ErrorIs(t, err, ErrServiceNotFound)
+	_, err = LoadGroup[testHANAConfig](env, "broken")
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"missing", "also-missing"}, m.notFound)
+	assert.Equal(t, []string{"broken", "broken"}, m.unmarshalErrors)
+
+	// failed loads are not counted
+	_, err = loadEnvFromBytes([]byte(`invalid`), FileSource, WithMetrics(m))
+	assert.Error(t, err)
+	assert.Len(t, m.loads, 1)
+
+	// nothing is recorded by default
+	env, err = loadEnvFromBytes([]byte(testGroupEnv), FileSource, WithMetrics(nil))
+	assert.NoError(t, err)
+	assert.Error(t, env.LoadService(&config, "missing"))
+	assert.Error(t, (&Env{}).LoadService(&config, "missing"))
+}
@@ -0,0 +1,35 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MustLoadEnv is like LoadEnv but panics if the environment configuration cannot be loaded.
+// It is a convenience wrapper intended for quick scripts and init functions.
+func MustLoadEnv(opts ...Option) *Env {
+	env, err := LoadEnv(opts...)
+	if err != nil {
+		panic(err)
+	}
+	return env
+}
+
+// MustLoadService is like LoadService but panics if the service cannot be found or unmarshaled.
+// It is a convenience wrapper intended for quick scripts and init functions.
+func (e *Env) MustLoadService(target any, name string) {
+	if err := e.LoadService(target, name); err != nil {
+		panic(err)
+	}
+}
+
+// MustRaw is like Raw but panics if the service cannot be found.
+// The panic value is an error wrapping ErrServiceNotFound, so it can be recovered and checked with errors.Is.
+// It is meant for cases where a missing service is a programming error.
+func (e *Env) MustRaw(name string) json.RawMessage {
+	msg, ok := e.Raw(name)
+	if !ok {
+		panic(fmt.Errorf("%w: %s", ErrServiceNotFound, name))
+	}
+	return msg
+}
@@ -0,0 +1,52 @@
+package xsenv
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMustLoadEnv(t *testing.T) {
+	t.Setenv(EnvironmentKey, `{"VCAP_SERVICES": {"test_service": [{"name": "test"}]}}`)
+	assert.NotPanics(t, func() {
+		env := MustLoadEnv()
+		assert.Equal(t, EnvironmentSource, env.Source)
+	})
+
+	t.Setenv(EnvironmentKey, `not json`)
+	assert.Panics(t, func() {
+		MustLoadEnv()
+	})
+}
+
+func TestMustLoadService(t *testing.T) {
+	env, _ := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"test_service": [{"name": "test"}]}}`), RawSource)
+
+	mockService := new(MockUnmarshalService)
+	mockService.On("UnmarshalService", mock.Anything).Return(nil)
+	assert.NotPanics(t, func() {
+		env.MustLoadService(mockService, "test")
+	})
+
+	defer func() {
+		err, _ := recover().(error)
+		assert.True(t, errors.Is(err, ErrServiceNotFound))
+	}()
+	env.MustLoadService(mockService, "nonexistent")
+	t.Fatal("expected panic")
+}
+
+func TestMustRaw(t *testing.T) {
+	env, _ := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"test_service": [{"name": "test"}]}}`), RawSource)
+	assert.JSONEq(t, `{"name": "test"}`, string(env.MustRaw("TEST")))
+
+	defer func() {
+		err, _ := recover().(error)
+		assert.ErrorIs(t, err, ErrServiceNotFound)
+		assert.ErrorContains(t, err, "nonexistent")
+	}()
+	env.MustRaw("nonexistent")
+	t.Fatal("expected panic")
+}
@@ -0,0 +1,163 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// defaultGroup is the group services are put in when they are not part of any group, e.g. when added via Set.
+const defaultGroup = "user-provided"
+
+// Set sets the credentials of the service with the given name.
+// If the service exists, only its credentials are replaced and all other fields are kept.
+// Otherwise, a new service with the given name and credentials is added to the "user-provided" group.
+func (e *Env) Set(name string, credentials any) error {
+	creds, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+
+	if key, ok := e.lookup(name); ok {
+		msg := e.ServicesByName[key]
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(*msg, &fields); err != nil {
+			return err
+		}
+		if fields == nil {
+			fields = make(map[string]json.RawMessage)
+		}
+		credentialsKey, _ := credentialsField(fields, e.opts.credentials())
+		if credentialsKey == "" {
+			credentialsKey = e.opts.credentials()
+		}
+		fields[credentialsKey] = creds
+		data, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+		raw := json.RawMessage(data)
+		e.ServicesByName[key] = &raw
+		e.cache.invalidate(key)
+		return nil
+	}
+
+	e.addCredentials(name, creds)
+	return nil
+}
+
+// addCredentials adds a new service with the given name and credentials to the "user-provided" group.
+func (e *Env) addCredentials(name string, creds json.RawMessage) {
+	nameField, _ := json.Marshal(name)
+	credentialsKey, _ := json.Marshal(e.opts.credentials())
+	raw := json.RawMessage(`{"name":` + string(nameField) + `,` + string(credentialsKey) + `:` + string(creds) + `}`)
+	e.add(defaultGroup, name, &raw, RawSource, serviceMeta{Name: name})
+}
+
+// Remove removes the service with the given name.
+// It reports whether the service existed.
+func (e *Env) Remove(name string) bool {
+	key, ok := e.lookup(name)
+	if !ok {
+		return false
+	}
+	e.delete(key)
+	return true
+}
+
+// Marshal encodes the environment back into the VCAP_SERVICES shape,
+// i.e. an object holding the services grouped under the EnvironmentKey.
+// Services which are not part of any group are grouped by their label, or put into the "user-provided" group.
+func (e *Env) Marshal() ([]byte, error) {
+	groups := make(map[string][]*json.RawMessage)
+	grouped := make(map[string]bool)
+	for group, keys := range e.groups {
+		for _, key := range keys {
+			if msg, ok := e.ServicesByName[key]; ok {
+				groups[group] = append(groups[group], msg)
+				grouped[key] = true
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(e.ServicesByName))
+	for key := range e.ServicesByName {
+		if !grouped[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		group := e.metaOf(key).Label
+		if group == "" {
+			group = defaultGroup
+		}
+		groups[group] = append(groups[group], e.ServicesByName[key])
+	}
+
+	return json.Marshal(map[string]any{
+		EnvironmentKey: groups,
+	})
+}
+
+// Rename exposes the service named oldName as newName, e.g. to adapt a shared configuration to a component
+// expecting a specific binding name. The name field of the service is updated as well, while its group,
+// source and all other fields are kept.
+// It returns ErrServiceNotFound if oldName does not exist and ErrDuplicateService if newName is already taken.
+func (e *Env) Rename(oldName, newName string) error {
+	renamed, err := e.CopyWithMapping(map[string]string{oldName: newName})
+	if err != nil {
+		return err
+	}
+	*e = *renamed
+	return nil
+}
+
+// CopyWithMapping returns a copy of e in which the services named like the keys of mapping are
+// renamed to the corresponding values, see Rename. All other services are copied as is.
+// Services may swap their names. It returns ErrServiceNotFound if a service to rename does not exist
+// and ErrDuplicateService if two services would end up with the same name.
+func (e *Env) CopyWithMapping(mapping map[string]string) (*Env, error) {
+	names := make(map[string]string, len(e.ServicesByName))
+	for key := range e.ServicesByName {
+		names[key] = key
+	}
+	for oldName, newName := range mapping {
+		key, ok := e.lookup(oldName)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrServiceNotFound, oldName)
+		}
+		names[key] = newName
+	}
+
+	keys := make([]string, 0, len(names))
+	for key := range names {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	taken := make(map[string]string, len(names))
+	for _, key := range keys {
+		normalized := e.normalize(names[key])
+		if other, ok := taken[normalized]; ok {
+			return nil, fmt.Errorf("%w: %s collides with %s", ErrDuplicateService, names[key], names[other])
+		}
+		taken[normalized] = key
+	}
+	return e.remap(names), nil
+}
+
+// withName returns a copy of the service msg with its name field set to name.
+// Services which are not objects are returned unchanged.
+func withName(msg *json.RawMessage, name string) *json.RawMessage {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(*msg, &fields); err != nil || fields == nil {
+		return msg
+	}
+	fields["name"], _ = json.Marshal(name)
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return msg
+	}
+	raw := json.RawMessage(data)
+	return &raw
+}
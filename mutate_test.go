@@ -0,0 +1,116 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetRemoveRoundTrip(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {
+		"xsuaa": [{"name": "Portal-UAA", "label": "xsuaa", "credentials": {"clientid": "old"}}],
+		"hana": [{"name": "db", "label": "hana", "credentials": {"host": "db.example.com"}}]
+	}}`), FileSource)
+	assert.NoError(t, err)
+
+	// update keeps all other fields
+	assert.NoError(t, env.Set("portal-uaa", map[string]string{"clientid": "new"}))
+	// add
+	assert.NoError(t, env.Set("custom", map[string]any{"url": "https://example.com"}))
+	// remove
+	assert.True(t, env.Remove("DB"))
+	assert.False(t, env.Remove("db"))
+
+	data, err := env.Marshal()
+	assert.NoError(t, err)
+
+	reloaded, err := loadEnvFromBytes(data, RawSource)
+	assert.NoError(t, err)
+	assert.Len(t, reloaded.ServicesByName, 2)
+	assert.JSONEq(t, `{"name": "Portal-UAA", "label": "xsuaa", "credentials": {"clientid": "new"}}`,
+		string(*reloaded.ServicesByName["Portal-UAA"]))
+	assert.JSONEq(t, `{"name": "custom", "credentials": {"url": "https://example.com"}}`,
+		string(*reloaded.ServicesByName["custom"]))
+	assert.NotContains(t, reloaded.ServicesByName, "db")
+	assert.Equal(t, []string{"Portal-UAA"}, reloaded.groups["xsuaa"])
+	assert.Equal(t, []string{"custom"}, reloaded.groups[defaultGroup])
+	assert.NotContains(t, reloaded.groups, "hana")
+
+	source, _ := env.SourceOf("custom")
+	assert.Equal(t, RawSource, source)
+	source, _ = env.SourceOf("portal-uaa")
+	assert.Equal(t, FileSource, source)
+}
+
+func TestSetInvalidCredentials(t *testing.T) {
+	env := &Env{}
+	assert.Error(t, env.Set("invalid", make(chan int)))
+	assert.Empty(t, env.ServicesByName)
+}
+
+func TestMarshalUngrouped(t *testing.T) {
+	uaa := json.RawMessage(`{"name": "uaa", "label": "xsuaa"}`)
+	custom := json.RawMessage(`{"name": "custom"}`)
+	env := &Env{ServicesByName: map[string]*json.RawMessage{"uaa": &uaa, "custom": &custom}}
+
+	data, err := env.Marshal()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"VCAP_SERVICES": {
+		"xsuaa": [{"name": "uaa", "label": "xsuaa"}],
+		"user-provided": [{"name": "custom"}]
+	}}`, string(data))
+}
+
+func TestRename(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"hana": [
+		{"name": "db-1", "label": "hana", "credentials": {"host": "one"}},
+		{"name": "db-2", "label": "hana", "credentials": {"host": "two"}},
+		{"name": "db-3", "label": "hana", "credentials": {"host": "three"}}
+	]}}`), FileSource)
+	assert.NoError(t, err)
+
+	assert.NoError(t, env.Rename("DB-2", "orders-db"))
+	assert.Equal(t, []string{"db-1", "db-3", "orders-db"}, env.Names())
+	raw, ok := env.Raw("orders-db")
+	assert.True(t, ok)
+	assert.JSONEq(t, `{"name": "orders-db", "label": "hana", "credentials": {"host": "two"}}`, string(raw))
+	source, _ := env.SourceOf("orders-db")
+	assert.Equal(t, FileSource, source)
+	assert.NoError(t, env.AssertLabel("orders-db", "hana"))
+
+	// the order within the group is kept
+	named, err := LoadGroup[testNamedService](env, "hana")
+	assert.NoError(t, err)
+	assert.Equal(t, []testNamedService{{Name: "db-1"}, {Name: "orders-db"}, {Name: "db-3"}}, named)
+
+	assert.ErrorIs(t, env.Rename("db-2", "other"), ErrServiceNotFound)
+	assert.ErrorIs(t, env.Rename("db-1", "DB-3"), ErrDuplicateService)
+	assert.Equal(t, []string{"db-1", "db-3", "orders-db"}, env.Names())
+}
+
+func TestCopyWithMapping(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"hana": [
+		{"name": "db-1"}, {"name": "db-2"}, {"name": "other"}
+	]}}`), RawSource)
+	assert.NoError(t, err)
+
+	// names may be swapped
+	swapped, err := env.CopyWithMapping(map[string]string{"db-1": "db-2", "db-2": "db-1"})
+	assert.NoError(t, err)
+	named, err := LoadGroup[testNamedService](swapped, "hana")
+	assert.NoError(t, err)
+	assert.Equal(t, []testNamedService{{Name: "db-2"}, {Name: "db-1"}, {Name: "other"}}, named)
+
+	// the original is left untouched
+	named, err = LoadGroup[testNamedService](env, "hana")
+	assert.NoError(t, err)
+	assert.Equal(t, []testNamedService{{Name: "db-1"}, {Name: "db-2"}, {Name: "other"}}, named)
+
+	_, err = env.CopyWithMapping(map[string]string{"db-1": "other"})
+	assert.ErrorIs(t, err, ErrDuplicateService)
+	_, err = env.CopyWithMapping(map[string]string{"db-1": "x", "db-2": "X"})
+	assert.ErrorIs(t, err, ErrDuplicateService)
+	_, err = env.CopyWithMapping(map[string]string{"missing": "x"})
+	assert.ErrorIs(t, err, ErrServiceNotFound)
+}
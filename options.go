@@ -0,0 +1,323 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Option configures how an Env is loaded and how services are looked up.
+type Option func(*options)
+
+// options holds the configuration assembled from a list of Option values.
+type options struct {
+	normalize    func(string) string
+	emptyNames   EmptyNamePolicy
+	logger       *slog.Logger
+	envKey       string
+	minServices  int
+	mergeSources bool
+	decodeCache  bool
+
+	serviceOverrides bool
+	fieldTransformer func(path, value string) string
+	base64           bool
+	gzip             bool
+	optionalFiles    bool
+	maxSize          int64
+	maxDepth         int
+	allowMissing     bool
+	prefix           string
+
+	environmentSection string
+	metrics            MetricsRecorder
+	rawTransformer     func(name string, msg json.RawMessage) (json.RawMessage, error)
+	skipInvalid        bool
+	aliases            map[string]string
+	fileReferences     []string
+	fileBaseDir        string
+	duplicates         DuplicateStrategy
+	credentialsKey     string
+	unquoteCredentials bool
+}
+
+// newOptions applies opts on top of the default configuration.
+func newOptions(opts []Option) *options {
+	o := &options{
+		normalize: DefaultNormalizer,
+		envKey:    EnvironmentKey,
+		metrics:   noopMetrics{},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// CallOption configures a single call of a function working on a loaded Env, like Env.LoadServices,
+// while Option configures how an Env is loaded. Every CallOption documents the functions it applies to.
+type CallOption func(*callOptions)
+
+// callOptions holds the configuration assembled from a list of CallOption values.
+type callOptions struct {
+	failFast         bool
+	skipDecodeErrors bool
+	pollInterval     time.Duration
+}
+
+// newCallOptions applies opts on top of the default configuration.
+func newCallOptions(opts []CallOption) *callOptions {
+	o := &callOptions{
+		pollInterval: DefaultPollInterval,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// DefaultNormalizer is the default normalizer of service names. It applies Unicode NFC normalization
+// and lowercases the name, so names match regardless of case and of composed or decomposed characters,
+// e.g. "Café-Svc" written with a combining accent matches "café-svc".
+func DefaultNormalizer(name string) string {
+	return strings.ToLower(norm.NFC.String(name))
+}
+
+// WithNameNormalizer sets the function used to normalize service names.
+// The normalizer is applied both when indexing services and when looking them up,
+// so lookups stay consistent. It defaults to DefaultNormalizer, use strings.ToLower to opt out of
+// Unicode normalization.
+func WithNameNormalizer(normalize func(string) string) Option {
+	return func(o *options) {
+		if normalize != nil {
+			o.normalize = normalize
+		}
+	}
+}
+
+// WithStripPrefix removes prefix from the names of services while indexing them, e.g. a space prefix like "prod_",
+// so services can be referenced the same way in every landscape. The prefix is matched ignoring case.
+// Lookups work with both the stripped and the prefixed names. Names consisting of the prefix only are kept.
+func WithStripPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// stripPrefix removes the prefix set using WithStripPrefix from name.
+func (o *options) stripPrefix(name string) string {
+	n := len(o.prefix)
+	if n == 0 || len(name) <= n || !strings.EqualFold(name[:n], o.prefix) {
+		return name
+	}
+	return name[n:]
+}
+
+// warn logs a warning if a logger was configured.
+func (o *options) warn(msg string, args ...any) {
+	if o != nil && o.logger != nil {
+		o.logger.Warn(msg, args...)
+	}
+}
+
+// WithLogger sets a logger used to report warnings, e.g. about skipped services.
+// By default, nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// EmptyNamePolicy controls how services without a name are handled while loading.
+type EmptyNamePolicy int
+
+const (
+	// SkipEmptyNames skips services without a name, logging a warning if a logger is configured.
+	SkipEmptyNames EmptyNamePolicy = iota
+	// RejectEmptyNames fails loading with ErrEmptyServiceName.
+	RejectEmptyNames
+	// FallbackEmptyNames indexes services without a name by their binding_name or, if absent, their label.
+	// Services having neither are skipped.
+	FallbackEmptyNames
+)
+
+// WithEmptyNames sets the policy for services without a name. It defaults to SkipEmptyNames.
+func WithEmptyNames(policy EmptyNamePolicy) Option {
+	return func(o *options) {
+		o.emptyNames = policy
+	}
+}
+
+// DuplicateStrategy controls how services with the same normalized name are handled while loading.
+// Groups are processed in sorted order and services in the order of their group.
+type DuplicateStrategy int
+
+const (
+	// LastWins keeps the last service with a name, replacing the ones before, logging a warning if a logger is configured.
+	LastWins DuplicateStrategy = iota
+	// FirstWins keeps the first service with a name, skipping the ones after, logging a warning if a logger is configured.
+	FirstWins
+	// RejectDuplicates fails loading with ErrDuplicateService.
+	RejectDuplicates
+)
+
+// WithDuplicateStrategy sets the strategy for services with the same name. It defaults to LastWins.
+func WithDuplicateStrategy(strategy DuplicateStrategy) Option {
+	return func(o *options) {
+		o.duplicates = strategy
+	}
+}
+
+// WithEnvKey sets the name of the variable holding the configuration, e.g. when using LoadEnv or LoadEnvFromDotEnv.
+// It defaults to EnvironmentKey.
+func WithEnvKey(key string) Option {
+	return func(o *options) {
+		o.envKey = key
+	}
+}
+
+// WithMinServices makes loading fail with ErrTooFewServices if fewer than n services were parsed.
+// This turns an empty or misnamed configuration into a loud failure. It defaults to 0 (no requirement).
+func WithMinServices(n int) Option {
+	return func(o *options) {
+		o.minServices = n
+	}
+}
+
+// validate checks a freshly loaded env against the requirements of the options.
+func (o *options) validate(env *Env) error {
+	if n := len(env.ServicesByName); n < o.minServices {
+		return fmt.Errorf("%w: found %d, expected at least %d", ErrTooFewServices, n, o.minServices)
+	}
+	return nil
+}
+
+// WithMergeSources makes LoadEnv load both the environment variable and the default file and merge them,
+// instead of using only the first one available.
+// Services from the environment variable take precedence over services with the same name from the file,
+// so shared services come from the deployment while local-only services come from the file.
+func WithMergeSources() Option {
+	return func(o *options) {
+		o.mergeSources = true
+	}
+}
+
+// WithDecodeCache enables caching of values decoded using Get.
+// It is disabled by default to avoid retaining decoded values in memory unexpectedly.
+func WithDecodeCache() Option {
+	return func(o *options) {
+		o.decodeCache = true
+	}
+}
+
+// WithFieldTransformer sets a function applied to every string value of a service before it is decoded,
+// e.g. to trim whitespace or trailing slashes of URLs across all services.
+// The path of a value is dot separated and relative to the service, like "credentials.url" or "tags.0".
+// Only strings are passed to transform, the structure of the service is left intact.
+// The stored services are not changed, so Raw still returns the original values.
+func WithFieldTransformer(transform func(path, value string) string) Option {
+	return func(o *options) {
+		o.fieldTransformer = transform
+	}
+}
+
+// WithFailFast makes Env.LoadServices return the first failure instead of attempting every service
+// and joining all failures.
+func WithFailFast() CallOption {
+	return func(o *callOptions) {
+		o.failFast = true
+	}
+}
+
+// WithOptionalFiles makes LoadEnvFromFiles skip files which do not exist instead of failing.
+func WithOptionalFiles() Option {
+	return func(o *options) {
+		o.optionalFiles = true
+	}
+}
+
+// WithSkipDecodeErrors makes LoadGroupMap leave out services failing to decode instead of failing.
+func WithSkipDecodeErrors() CallOption {
+	return func(o *callOptions) {
+		o.skipDecodeErrors = true
+	}
+}
+
+// WithAllowMissing makes LoadEnv return an empty Env with NoneSource instead of an error
+// if neither the environment variable nor the default file is present,
+// e.g. for components using services only conditionally.
+func WithAllowMissing() Option {
+	return func(o *options) {
+		o.allowMissing = true
+	}
+}
+
+// WithRawTransformer sets a function rewriting the raw configuration of every service before it is stored,
+// e.g. to inject computed fields or to adapt non-standard binding shapes.
+// It is called with the name extracted from the original service, the returned service is indexed under this name.
+// An error returned by transform aborts loading.
+func WithRawTransformer(transform func(name string, msg json.RawMessage) (json.RawMessage, error)) Option {
+	return func(o *options) {
+		o.rawTransformer = transform
+	}
+}
+
+// WithSkipInvalid makes loading skip services whose metadata, like their name, cannot be parsed, instead of failing,
+// so a single malformed binding does not prevent using the others. Skipped services are logged as warnings
+// and their errors are available using Env.Skipped. By default, loading fails.
+func WithSkipInvalid() Option {
+	return func(o *options) {
+		o.skipInvalid = true
+	}
+}
+
+// WithAliases sets alternative names for services, mapping an alias to the actual name of a service,
+// e.g. "uaa" to "uaa-prod", so application code does not depend on landscape-specific names.
+// Aliases are consulted by lookups like LoadService and Raw only if no service has the requested name,
+// so a direct match always wins. Aliases are matched using the name normalizer.
+func WithAliases(aliases map[string]string) Option {
+	return func(o *options) {
+		if o.aliases == nil {
+			o.aliases = make(map[string]string, len(aliases))
+		}
+		for alias, name := range aliases {
+			o.aliases[alias] = name
+		}
+	}
+}
+
+// DefaultCredentialsKey is the key holding the credentials of a service, see WithCredentialsKey.
+const DefaultCredentialsKey = "credentials"
+
+// WithCredentialsKey sets the key holding the credentials of services, for brokers using e.g. "credential" or
+// "binding" instead of "credentials". The key is stored on the loaded Env, so decoding services and helpers like
+// RawCredentials and Set respect it. Implementations of UnmarshalService receive services with their credentials
+// moved to the standard "credentials" key, so they work with any broker.
+// It defaults to DefaultCredentialsKey.
+func WithCredentialsKey(key string) Option {
+	return func(o *options) {
+		o.credentialsKey = key
+	}
+}
+
+// credentials returns the key holding the credentials of services.
+func (o *options) credentials() string {
+	if o == nil || o.credentialsKey == "" {
+		return DefaultCredentialsKey
+	}
+	return o.credentialsKey
+}
+
+// WithUnquoteCredentials makes decoding services accept credentials which a broker encoded twice,
+// i.e. a JSON string holding the JSON object of the credentials. Such credentials are decoded before unmarshaling
+// them into the target. Credentials which are strings not holding a JSON object or array are left as they are,
+// so regular bindings are not affected.
+func WithUnquoteCredentials() Option {
+	return func(o *options) {
+		o.unquoteCredentials = true
+	}
+}
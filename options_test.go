@@ -0,0 +1,377 @@
+package xsenv
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWithNameNormalizer(t *testing.T) {
+	data := `{"VCAP_SERVICES": {"test_service": [{"name": "myapp-Test"}]}}`
+	normalize := func(name string) string {
+		return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(name)), "myapp-")
+	}
+	env, err := loadEnvFromBytes([]byte(data), RawSource, WithNameNormalizer(normalize))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"myapp-Test"}, env.Names())
+	assert.Equal(t, map[string]string{"test": "myapp-Test"}, env.index)
+
+	mockService := new(MockUnmarshalService)
+	mockService.On("UnmarshalService", mock.Anything).Return(nil)
+
+	// lookups use the same normalizer as indexing
+	assert.NoError(t, env.LoadService(mockService, " MyApp-TEST "))
+	assert.NoError(t, env.LoadService(mockService, "test"))
+	mockService.AssertExpectations(t)
+}
+
+func TestWithStripPrefix(t *testing.T) {
+	data := []byte(`{"VCAP_SERVICES": {"xsuaa": [
+		{"name": "prod_portal-uaa"}, {"name": "PROD_portal-db"}, {"name": "shared"}, {"name": "prod_"}
+	]}}`)
+	env, err := loadEnvFromBytes(data, RawSource, WithStripPrefix("prod_"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"portal-db", "portal-uaa", "prod_", "shared"}, env.Names())
+
+	for _, name := range []string{"portal-uaa", "Portal-UAA", "prod_portal-uaa"} {
+		_, ok := env.Raw(name)
+		assert.True(t, ok, name)
+	}
+	view, ok := env.Find(ByName("portal-db"))
+	assert.True(t, ok)
+	assert.Equal(t, "portal-db", view.Name)
+
+	// the raw service keeps its name
+	raw, _ := env.Raw("portal-uaa")
+	assert.JSONEq(t, `{"name": "prod_portal-uaa"}`, string(raw))
+}
+
+func TestDefaultNameNormalizer(t *testing.T) {
+	env := &Env{ServicesByName: map[string]*json.RawMessage{}}
+	assert.Equal(t, "portal-uaa", env.normalize("Portal-UAA"))
+	assert.Equal(t, "caf\u00e9-svc", env.normalize("Cafe\u0301-Svc"))
+}
+
+func TestUnicodeNormalization(t *testing.T) {
+	const composed, decomposed = "caf\u00e9-svc", "cafe\u0301-svc"
+	data := []byte(`{"VCAP_SERVICES": {"a": [{"name": "` + decomposed + `"}]}}`)
+
+	env, err := loadEnvFromBytes(data, RawSource)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{decomposed}, env.Names())
+	_, ok := env.Raw(composed)
+	assert.True(t, ok)
+	_, ok = env.Raw(strings.ToUpper(decomposed))
+	assert.True(t, ok)
+
+	// opting out of Unicode normalization
+	env, err = loadEnvFromBytes(data, RawSource, WithNameNormalizer(strings.ToLower))
+	assert.NoError(t, err)
+	_, ok = env.Raw(composed)
+	assert.False(t, ok)
+	_, ok = env.Raw(decomposed)
+	assert.True(t, ok)
+}
+
+func TestWithEmptyNames(t *testing.T) {
+	data := []byte(`{"VCAP_SERVICES": {"xsuaa": [
+		{"name": "named"},
+		{"binding_name": "bound", "label": "xsuaa"},
+		{"label": "xsuaa"},
+		{"name": ""}
+	]}}`)
+
+	t.Run("skip by default", func(t *testing.T) {
+		var logs bytes.Buffer
+		env, err := loadEnvFromBytes(data, RawSource, WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+		assert.NoError(t, err)
+		assert.Len(t, env.ServicesByName, 1)
+		assert.Contains(t, env.ServicesByName, "named")
+		assert.NotContains(t, env.ServicesByName, "")
+		assert.Equal(t, 3, strings.Count(logs.String(), "skipping service without name"))
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		_, err := loadEnvFromBytes(data, RawSource, WithEmptyNames(RejectEmptyNames))
+		assert.ErrorIs(t, err, ErrEmptyServiceName)
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		env, err := loadEnvFromBytes(data, RawSource, WithEmptyNames(FallbackEmptyNames))
+		assert.NoError(t, err)
+		assert.Len(t, env.ServicesByName, 3)
+		assert.Contains(t, env.ServicesByName, "named")
+		assert.Contains(t, env.ServicesByName, "bound")
+		assert.Contains(t, env.ServicesByName, "xsuaa")
+	})
+}
+
+func TestWithEnvKey(t *testing.T) {
+	t.Setenv("CUSTOM_SERVICES", `{"VCAP_SERVICES": {"test_service": [{"name": "custom"}]}}`)
+	env, err := LoadEnv(WithEnvKey("CUSTOM_SERVICES"))
+	assert.NoError(t, err)
+	assert.Equal(t, EnvironmentSource, env.Source)
+	assert.Equal(t, []string{"custom"}, env.Names())
+}
+
+func TestWithMinServices(t *testing.T) {
+	data := []byte(`{"VCAP_SERVICES": {"xsuaa": [{"name": "a"}, {"name": "b"}]}}`)
+
+	_, err := loadEnvFromBytes(data, RawSource, WithMinServices(2))
+	assert.NoError(t, err)
+
+	_, err = loadEnvFromBytes(data, RawSource, WithMinServices(3))
+	assert.ErrorIs(t, err, ErrTooFewServices)
+	assert.ErrorContains(t, err, "found 2, expected at least 3")
+
+	_, err = LoadEnvFromReaderStreaming(bytes.NewReader([]byte(`{}`)), WithMinServices(1))
+	assert.ErrorIs(t, err, ErrTooFewServices)
+
+	// no requirement by default
+	_, err = loadEnvFromBytes([]byte(`{}`), RawSource)
+	assert.NoError(t, err)
+}
+
+func TestWithMergeSources(t *testing.T) {
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	assert.NoError(t, os.Chdir(dir))
+	defer func() {
+		_ = os.Chdir(wd)
+	}()
+
+	// file only
+	assert.NoError(t, os.WriteFile(DefaultEnvFile, []byte(`{"VCAP_SERVICES": {"hana": [
+		{"name": "shared", "origin": "file"},
+		{"name": "local", "origin": "file"}
+	]}}`), 0o600))
+	env, err := LoadEnv(WithMergeSources())
+	assert.NoError(t, err)
+	assert.Equal(t, FileSource, env.Source)
+	assert.Equal(t, []string{"local", "shared"}, env.Names())
+
+	// both, the environment variable wins
+	t.Setenv(EnvironmentKey, `{"VCAP_SERVICES": {"hana": [{"name": "shared", "origin": "env"}]}}`)
+	env, err = LoadEnv(WithMergeSources(), WithMinServices(2))
+	assert.NoError(t, err)
+	assert.Equal(t, MergedSource, env.Source)
+	assert.Equal(t, []string{"local", "shared"}, env.Names())
+	assert.JSONEq(t, `{"name": "shared", "origin": "env"}`, string(*env.ServicesByName["shared"]))
+	source, _ := env.SourceOf("shared")
+	assert.Equal(t, EnvironmentSource, source)
+	source, _ = env.SourceOf("local")
+	assert.Equal(t, FileSource, source)
+
+	// requirements apply to the merged result
+	_, err = LoadEnv(WithMergeSources(), WithMinServices(3))
+	assert.ErrorIs(t, err, ErrTooFewServices)
+
+	// environment variable only
+	assert.NoError(t, os.Remove(DefaultEnvFile))
+	env, err = LoadEnv(WithMergeSources())
+	assert.NoError(t, err)
+	assert.Equal(t, EnvironmentSource, env.Source)
+	assert.Equal(t, []string{"shared"}, env.Names())
+
+	// neither
+	assert.NoError(t, os.Unsetenv(EnvironmentKey))
+	_, err = LoadEnv(WithMergeSources())
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	env, err = LoadEnv(WithMergeSources(), WithAllowMissing())
+	assert.NoError(t, err)
+	assert.Equal(t, NoneSource, env.Source)
+}
+
+func TestWithAllowMissing(t *testing.T) {
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	assert.NoError(t, os.Chdir(dir))
+	defer func() {
+		_ = os.Chdir(wd)
+	}()
+
+	_, err := LoadEnv()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	env, err := LoadEnv(WithAllowMissing())
+	assert.NoError(t, err)
+	assert.Equal(t, NoneSource, env.Source)
+	assert.Empty(t, env.Names())
+
+	// requirements still apply
+	_, err = LoadEnv(WithAllowMissing(), WithMinServices(1))
+	assert.ErrorIs(t, err, ErrTooFewServices)
+
+	// other errors are reported
+	assert.NoError(t, os.WriteFile(DefaultEnvFile, []byte(`not json`), 0o600))
+	_, err = LoadEnv(WithAllowMissing())
+	assert.Error(t, err)
+}
+
+func TestWithRawTransformer(t *testing.T) {
+	data := []byte(`{"VCAP_SERVICES": {"custom": [
+		{"name": "db", "credentials": {"hostname": "db.example.com"}},
+		{"name": "plain"}
+	]}}`)
+	var names []string
+	env, err := loadEnvFromBytes(data, RawSource, WithRawTransformer(func(name string, msg json.RawMessage) (json.RawMessage, error) {
+		names = append(names, name)
+		if name != "db" {
+			return msg, nil
+		}
+		return json.RawMessage(`{"name": "renamed", "label": "postgresql", "credentials": {"host": "db.example.com"}}`), nil
+	}))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"db", "plain"}, names)
+	assert.Equal(t, []string{"db", "plain"}, env.Names())
+
+	var config testHANAConfig
+	assert.NoError(t, env.LoadService(&config, "db"))
+	assert.Equal(t, "db.example.com", config.Host)
+	assert.NoError(t, env.AssertLabel("db", "postgresql"))
+
+	_, err = loadEnvFromBytes(data, RawSource, WithRawTransformer(func(name string, msg json.RawMessage) (json.RawMessage, error) {
+		return nil, errors.New("unsupported shape")
+	}))
+	assert.EqualError(t, err, "db: unsupported shape")
+
+	_, err = loadEnvFromBytes(data, RawSource, WithRawTransformer(func(name string, msg json.RawMessage) (json.RawMessage, error) {
+		return json.RawMessage(`not json`), nil
+	}))
+	assert.Error(t, err)
+}
+
+func TestWithSkipInvalid(t *testing.T) {
+	data := []byte(`{"VCAP_SERVICES": {
+		"xsuaa": [{"name": "uaa"}, {"name": 42}],
+		"hana": [{"name": ["db"], "tags": "not-a-list"}]
+	}}`)
+
+	_, err := loadEnvFromBytes(data, RawSource)
+	assert.Error(t, err)
+
+	env, err := loadEnvFromBytes(data, RawSource, WithSkipInvalid())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"uaa"}, env.Names())
+	assert.Len(t, env.Skipped(), 2)
+	for _, err := range env.Skipped() {
+		var typeErr *json.UnmarshalTypeError
+		assert.ErrorAs(t, err, &typeErr)
+	}
+
+	// skipped services are kept when merging
+	merged := env.Merge(newEnv(RawSource, newOptions(nil)))
+	assert.Len(t, merged.Skipped(), 2)
+
+	// skipped services do not count towards the minimum
+	_, err = loadEnvFromBytes(data, RawSource, WithSkipInvalid(), WithMinServices(2))
+	assert.ErrorIs(t, err, ErrTooFewServices)
+}
+
+func TestWithAliases(t *testing.T) {
+	data := []byte(`{"VCAP_SERVICES": {"xsuaa": [
+		{"name": "uaa-prod", "credentials": {"host": "prod.example.com"}},
+		{"name": "db", "credentials": {"host": "db.example.com"}}
+	]}}`)
+	env, err := loadEnvFromBytes(data, RawSource, WithAliases(map[string]string{
+		"uaa":   "uaa-prod",
+		"hana":  "db",
+		"other": "missing",
+	}))
+	assert.NoError(t, err)
+
+	var config testHANAConfig
+	assert.NoError(t, env.LoadService(&config, "UAA"))
+	assert.Equal(t, "prod.example.com", config.Host)
+	_, ok := env.Raw("hana")
+	assert.True(t, ok)
+	assert.ErrorIs(t, env.LoadService(&config, "other"), ErrServiceNotFound)
+
+	// a direct match wins over an alias
+	assert.NoError(t, env.Set("uaa", map[string]string{"host": "direct.example.com"}))
+	assert.NoError(t, env.LoadService(&config, "uaa"))
+	assert.Equal(t, "direct.example.com", config.Host)
+}
+
+func TestWithDuplicateStrategy(t *testing.T) {
+	data := []byte(`{"VCAP_SERVICES": {
+		"xsuaa": [{"name": "uaa", "credentials": {"host": "second"}}, {"name": "UAA", "credentials": {"host": "third"}}],
+		"hana": [{"name": "uaa", "credentials": {"host": "first"}}, {"name": "db"}]
+	}}`)
+	var config testHANAConfig
+
+	env, err := loadEnvFromBytes(data, RawSource)
+	assert.NoError(t, err)
+	assert.NoError(t, env.LoadService(&config, "uaa"))
+	assert.Equal(t, "third", config.Host)
+	assert.Equal(t, []string{"UAA", "db"}, env.Names())
+
+	env, err = loadEnvFromBytes(data, RawSource, WithDuplicateStrategy(LastWins))
+	assert.NoError(t, err)
+	assert.NoError(t, env.LoadService(&config, "uaa"))
+	assert.Equal(t, "third", config.Host)
+
+	env, err = loadEnvFromBytes(data, RawSource, WithDuplicateStrategy(FirstWins))
+	assert.NoError(t, err)
+	assert.NoError(t, env.LoadService(&config, "uaa"))
+	assert.Equal(t, "first", config.Host)
+	assert.Equal(t, []string{"db", "uaa"}, env.Names())
+
+	_, err = loadEnvFromBytes(data, RawSource, WithDuplicateStrategy(RejectDuplicates))
+	assert.ErrorIs(t, err, ErrDuplicateService)
+	assert.EqualError(t, err, "duplicate service name: uaa in group xsuaa collides with uaa")
+
+	_, err = loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"xsuaa": [{"name": "uaa"}, {"name": "db"}]}}`),
+		RawSource, WithDuplicateStrategy(RejectDuplicates))
+	assert.NoError(t, err)
+}
+
+func TestWithCredentialsKey(t *testing.T) {
+	data := []byte(`{"VCAP_SERVICES": {"custom-broker": [
+		{"name": "db", "binding": {"host": "db.example.com", "port": "30015"}, "credentials": {"host": "ignored"}},
+		{"name": "empty"}
+	]}}`)
+	env, err := loadEnvFromBytes(data, RawSource, WithCredentialsKey("binding"))
+	assert.NoError(t, err)
+
+	var config testHANAConfig
+	assert.NoError(t, env.LoadService(&config, "db"))
+	assert.Equal(t, testHANAConfig{Host: "db.example.com", Port: "30015"}, config)
+
+	// UnmarshalCredentials receives the credentials of the configured key
+	var creds testCredentials
+	assert.NoError(t, env.LoadService(&creds, "db"))
+	assert.Equal(t, "db.example.com", creds.Host)
+
+	raw, err := env.RawCredentials("db")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"host": "db.example.com", "port": "30015"}`, string(raw))
+	keys, err := env.CredentialKeys("db")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"host", "port"}, keys)
+	_, err = env.RawCredentials("empty")
+	assert.ErrorIs(t, err, ErrNoCredentials)
+
+	assert.NoError(t, env.Set("db", map[string]string{"host": "other.example.com"}))
+	assert.NoError(t, env.Set("added", map[string]string{"host": "added.example.com"}))
+	for name, host := range map[string]string{"db": "other.example.com", "added": "added.example.com"} {
+		config = testHANAConfig{}
+		assert.NoError(t, env.LoadService(&config, name))
+		assert.Equal(t, host, config.Host)
+	}
+	msg, _ := env.Raw("added")
+	assert.JSONEq(t, `{"name": "added", "binding": {"host": "added.example.com"}}`, string(msg))
+
+	// the standard key is used by default
+	env, err = loadEnvFromBytes(data, RawSource)
+	assert.NoError(t, err)
+	assert.NoError(t, env.LoadService(&config, "db"))
+	assert.Equal(t, "ignored", config.Host)
+}
@@ -0,0 +1,48 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// OverridePrefix is the prefix of environment variables overriding single services, see WithServiceOverrides.
+const OverridePrefix = "XSENV_OVERRIDE_"
+
+// WithServiceOverrides makes loading apply overrides of single services from environment variables
+// after the configuration was loaded. A variable like XSENV_OVERRIDE_portal-uaa={"clientid": "..."}
+// replaces the credentials of the service named by the suffix, or adds it if it does not exist.
+// The suffix is normalized like any other service name.
+func WithServiceOverrides() Option {
+	return func(o *options) {
+		o.serviceOverrides = true
+	}
+}
+
+// applyServiceOverrides applies the service overrides found in the environment to env.
+func applyServiceOverrides(env *Env) error {
+	var variables []string
+	for _, variable := range os.Environ() {
+		if strings.HasPrefix(variable, OverridePrefix) {
+			variables = append(variables, variable)
+		}
+	}
+	sort.Strings(variables)
+
+	for _, variable := range variables {
+		key, value, _ := strings.Cut(variable, "=")
+		name := strings.TrimPrefix(key, OverridePrefix)
+		if name == "" {
+			continue
+		}
+		if err := env.Set(name, json.RawMessage(value)); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if key, ok := env.lookup(name); ok {
+			env.ServiceSources[key] = EnvironmentSource
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithServiceOverrides(t *testing.T) {
+	data := []byte(`{"VCAP_SERVICES": {"xsuaa": [
+		{"name": "Portal-UAA", "label": "xsuaa", "credentials": {"clientid": "original"}}
+	]}}`)
+	t.Setenv(OverridePrefix+"portal-uaa", `{"clientid": "override"}`)
+	t.Setenv(OverridePrefix+"new-service", `{"url": "https://example.com"}`)
+
+	env, err := loadEnvFromBytes(data, FileSource, WithServiceOverrides())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Portal-UAA", "new-service"}, env.Names())
+
+	raw, _ := env.Raw("portal-uaa")
+	assert.JSONEq(t, `{"name": "Portal-UAA", "label": "xsuaa", "credentials": {"clientid": "override"}}`, string(raw))
+	raw, _ = env.Raw("new-service")
+	assert.JSONEq(t, `{"name": "new-service", "credentials": {"url": "https://example.com"}}`, string(raw))
+
+	source, _ := env.SourceOf("portal-uaa")
+	assert.Equal(t, EnvironmentSource, source)
+
+	// overrides are only applied when enabled
+	env, err = loadEnvFromBytes(data, FileSource)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Portal-UAA"}, env.Names())
+
+	// invalid overrides fail loading
+	t.Setenv(OverridePrefix+"portal-uaa", `{invalid`)
+	_, err = loadEnvFromBytes(data, FileSource, WithServiceOverrides())
+	var syntaxErr *json.SyntaxError
+	assert.ErrorAs(t, err, &syntaxErr)
+	assert.ErrorContains(t, err, OverridePrefix+"portal-uaa")
+}
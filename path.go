@@ -0,0 +1,93 @@
+package xsenv
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrPathNotFound indicates that a path does not exist in a service.
+	ErrPathNotFound = errors.New("path not found")
+	// ErrIndexOutOfRange indicates that a path addresses an array element beyond the end of the array.
+	ErrIndexOutOfRange = errors.New("index out of range")
+	// ErrUnexpectedType indicates that a value found at a path has a different type than requested.
+	ErrUnexpectedType = errors.New("unexpected type")
+)
+
+// PathError records the path and segment a lookup failed at.
+type PathError struct {
+	// Path is the complete path that was looked up.
+	Path string
+	// Segment is the segment of Path the lookup failed at, empty if the value itself did not match.
+	Segment string
+	// Err is one of ErrPathNotFound, ErrIndexOutOfRange or ErrUnexpectedType, possibly wrapped.
+	Err error
+}
+
+func (e *PathError) Error() string {
+	if e.Segment == "" {
+		return fmt.Sprintf("%s: %v", e.Path, e.Err)
+	}
+	return fmt.Sprintf("%s: at %s: %v", e.Path, e.Segment, e.Err)
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// Lookup returns the value at the dot separated path in the service msg, e.g. "credentials.hosts.0".
+// Segments address object keys or, if the value is an array, element indices.
+// Objects are returned as map[string]any, arrays as []any and numbers as json.Number.
+// It returns a *PathError if the path does not exist.
+func Lookup(msg *json.RawMessage, path string) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(*msg))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return value, nil
+	}
+
+	for _, segment := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			child, ok := v[segment]
+			if !ok {
+				return nil, &PathError{Path: path, Segment: segment, Err: ErrPathNotFound}
+			}
+			value = child
+		case []any:
+			i, err := strconv.Atoi(segment)
+			if err != nil {
+				return nil, &PathError{Path: path, Segment: segment, Err: fmt.Errorf("%w: not an array index", ErrPathNotFound)}
+			}
+			if i < 0 || i >= len(v) {
+				return nil, &PathError{Path: path, Segment: segment, Err: fmt.Errorf("%w: length is %d", ErrIndexOutOfRange, len(v))}
+			}
+			value = v[i]
+		default:
+			return nil, &PathError{Path: path, Segment: segment, Err: ErrPathNotFound}
+		}
+	}
+	return value, nil
+}
+
+// GetString returns the string at the dot separated path in the service msg, see Lookup.
+// It returns a *PathError wrapping ErrUnexpectedType if the value is not a string.
+func GetString(msg *json.RawMessage, path string) (string, error) {
+	value, err := Lookup(msg, path)
+	if err != nil {
+		return "", err
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", &PathError{Path: path, Err: fmt.Errorf("%w: %T is not a string", ErrUnexpectedType, value)}
+	}
+	return s, nil
+}
@@ -0,0 +1,59 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookup(t *testing.T) {
+	msg := json.RawMessage(`{"name": "db", "credentials": {
+		"hosts": ["a.example.com", "b.example.com"],
+		"port": 30015,
+		"replicas": [{"host": "r1"}]
+	}}`)
+
+	testCases := []struct {
+		path     string
+		expected string
+		err      error
+	}{
+		{"name", "db", nil},
+		{"credentials.hosts.0", "a.example.com", nil},
+		{"credentials.hosts.1", "b.example.com", nil},
+		{"credentials.replicas.0.host", "r1", nil},
+		{"credentials.hosts.2", "", ErrIndexOutOfRange},
+		{"credentials.hosts.-1", "", ErrIndexOutOfRange},
+		{"credentials.hosts.first", "", ErrPathNotFound},
+		{"credentials.missing", "", ErrPathNotFound},
+		{"name.nested", "", ErrPathNotFound},
+		{"credentials.port", "", ErrUnexpectedType},
+		{"credentials", "", ErrUnexpectedType},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			s, err := GetString(&msg, tc.path)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				var pathErr *PathError
+				assert.ErrorAs(t, err, &pathErr)
+				assert.Equal(t, tc.path, pathErr.Path)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, s)
+		})
+	}
+
+	port, err := Lookup(&msg, "credentials.port")
+	assert.NoError(t, err)
+	assert.Equal(t, json.Number("30015"), port)
+
+	_, err = GetString(&msg, "credentials.hosts.5")
+	assert.EqualError(t, err, "credentials.hosts.5: at 5: index out of range: length is 2")
+
+	invalid := json.RawMessage(`{invalid`)
+	_, err = Lookup(&invalid, "name")
+	assert.Error(t, err)
+}
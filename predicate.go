@@ -0,0 +1,165 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ServiceView is a read-only view of a service, exposing its parsed metadata and raw configuration.
+type ServiceView struct {
+	// Name is the name the service is stored under, which is the fallback name for services without a name,
+	// see WithEmptyNames.
+	Name  string
+	Label string
+	Tags  []string
+	Plan  string
+	Raw   *json.RawMessage
+
+	InstanceName string
+	InstanceGUID string
+
+	// env is the Env the view belongs to, used to compare names like lookups do.
+	env *Env
+}
+
+// Predicate reports whether a service matches some criterion.
+type Predicate = func(ServiceView) bool
+
+// ByName matches services with the given name. Names are normalized like for lookups,
+// see WithNameNormalizer, or using DefaultNormalizer for views not returned by an Env.
+func ByName(name string) Predicate {
+	return func(s ServiceView) bool {
+		if s.env == nil {
+			return DefaultNormalizer(s.Name) == DefaultNormalizer(name)
+		}
+		return s.env.normalize(s.Name) == s.env.normalize(name)
+	}
+}
+
+// ByLabel matches services with the given label.
+func ByLabel(label string) Predicate {
+	return func(s ServiceView) bool {
+		return s.Label == label
+	}
+}
+
+// ByTag matches services carrying the given tag.
+func ByTag(tag string) Predicate {
+	return func(s ServiceView) bool {
+		return slices.Contains(s.Tags, tag)
+	}
+}
+
+// ByPlan matches services with the given plan.
+func ByPlan(plan string) Predicate {
+	return func(s ServiceView) bool {
+		return s.Plan == plan
+	}
+}
+
+// And matches services matching all the given predicates.
+func And(preds ...Predicate) Predicate {
+	return func(s ServiceView) bool {
+		for _, pred := range preds {
+			if !pred(s) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or matches services matching at least one of the given predicates.
+func Or(preds ...Predicate) Predicate {
+	return func(s ServiceView) bool {
+		for _, pred := range preds {
+			if pred(s) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not matches services not matching pred.
+func Not(pred Predicate) Predicate {
+	return func(s ServiceView) bool {
+		return !pred(s)
+	}
+}
+
+// Find returns the first service matching pred, in the order of the service names.
+// The second return value is false if no service matches.
+func (e *Env) Find(pred Predicate) (ServiceView, bool) {
+	for _, key := range e.sortedKeys() {
+		if view := e.view(key); pred(view) {
+			return view, true
+		}
+	}
+	return ServiceView{}, false
+}
+
+// FindByPrefix returns the sorted names of all services whose name starts with prefix,
+// e.g. "myapp-uaa-" to select among environment-scoped instances.
+// Names and prefix are normalized like for lookups.
+func (e *Env) FindByPrefix(prefix string) []string {
+	prefix = e.normalize(prefix)
+	var names []string
+	for _, key := range e.sortedKeys() {
+		if strings.HasPrefix(e.normalize(key), prefix) {
+			names = append(names, key)
+		}
+	}
+	return names
+}
+
+// FindByGlob returns the sorted names of all services whose name matches pattern, using the syntax of path.Match,
+// e.g. "myapp-*-prod". Names and pattern are normalized like for lookups.
+// It returns path.ErrBadPattern if the pattern is malformed.
+func (e *Env) FindByGlob(pattern string) ([]string, error) {
+	pattern = e.normalize(pattern)
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, key := range e.sortedKeys() {
+		if ok, _ := path.Match(pattern, e.normalize(key)); ok {
+			names = append(names, key)
+		}
+	}
+	return names, nil
+}
+
+// FindByRegexp returns the sorted names of all services whose name matches re, for naming schemes
+// not expressible as a glob, e.g. `^myapp-(uaa|db)-(dev|prod)$`.
+// Unlike FindByGlob, re is matched against the original, not normalized, name, so use the (?i) flag
+// to ignore case.
+func (e *Env) FindByRegexp(re *regexp.Regexp) []string {
+	var names []string
+	for _, key := range e.sortedKeys() {
+		if re.MatchString(key) {
+			names = append(names, key)
+		}
+	}
+	return names
+}
+
+// view returns the ServiceView of the service stored under key.
+func (e *Env) view(key string) ServiceView {
+	meta := e.metaOf(key)
+	return ServiceView{
+		Name:  key,
+		Label: meta.Label,
+		Tags:  meta.Tags,
+		Plan:  meta.Plan,
+		Raw:   e.ServicesByName[key],
+
+		InstanceName: meta.InstanceName,
+		InstanceGUID: meta.InstanceGUID,
+
+		env: e,
+	}
+}
@@ -0,0 +1,112 @@
+package xsenv
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPredicateEnv = `{"VCAP_SERVICES": {
+	"xsuaa": [
+		{"name": "uaa-app", "label": "xsuaa", "plan": "application", "tags": ["xsuaa"]},
+		{"name": "uaa-broker", "label": "xsuaa", "plan": "broker", "tags": ["xsuaa"]}
+	],
+	"postgresql-db": [
+		{"name": "db", "label": "postgresql-db", "plan": "trial", "tags": ["database", "relational"]}
+	]
+}}`
+
+func TestPredicates(t *testing.T) {
+	view := ServiceView{Name: "DB", Label: "postgresql-db", Tags: []string{"database", "relational"}, Plan: "trial"}
+
+	assert.True(t, ByName("db")(view))
+	assert.False(t, ByName("uaa")(view))
+	assert.True(t, ByLabel("postgresql-db")(view))
+	assert.False(t, ByLabel("xsuaa")(view))
+	assert.True(t, ByTag("relational")(view))
+	assert.False(t, ByTag("xsuaa")(view))
+	assert.True(t, ByPlan("trial")(view))
+	assert.False(t, ByPlan("standard")(view))
+
+	assert.True(t, And(ByLabel("postgresql-db"), ByTag("database"))(view))
+	assert.False(t, And(ByLabel("postgresql-db"), ByTag("xsuaa"))(view))
+	assert.True(t, And()(view))
+	assert.True(t, Or(ByPlan("standard"), ByPlan("trial"))(view))
+	assert.False(t, Or(ByPlan("standard"), ByPlan("lite"))(view))
+	assert.False(t, Or()(view))
+	assert.True(t, Not(ByLabel("xsuaa"))(view))
+}
+
+func TestFind(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(testPredicateEnv), RawSource)
+	assert.NoError(t, err)
+
+	view, ok := env.Find(And(ByLabel("xsuaa"), ByPlan("broker")))
+	assert.True(t, ok)
+	assert.Equal(t, "uaa-broker", view.Name)
+	assert.Equal(t, []string{"xsuaa"}, view.Tags)
+	assert.Equal(t, env.ServicesByName["uaa-broker"], view.Raw)
+
+	// first match in name order
+	view, ok = env.Find(ByLabel("xsuaa"))
+	assert.True(t, ok)
+	assert.Equal(t, "uaa-app", view.Name)
+
+	_, ok = env.Find(And(ByTag("database"), Not(ByTag("relational"))))
+	assert.False(t, ok)
+
+	// names are compared like for lookups
+	env, err = loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"xsuaa": [
+		{"binding_name": "bn", "label": "xsuaa"}, {"name": "Portal_UAA"}
+	]}}`), RawSource, WithEmptyNames(FallbackEmptyNames), WithNameNormalizer(func(name string) string {
+		return strings.ReplaceAll(strings.ToLower(name), "_", "-")
+	}))
+	assert.NoError(t, err)
+	view, ok = env.Find(ByName("bn"))
+	assert.True(t, ok)
+	assert.Equal(t, "bn", view.Name)
+	view, ok = env.Find(ByName("portal-uaa"))
+	assert.True(t, ok)
+	assert.Equal(t, "Portal_UAA", view.Name)
+}
+
+func TestFindByPrefixAndGlob(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"xsuaa": [
+		{"name": "MyApp-UAA-Prod"}, {"name": "myapp-uaa-staging"}, {"name": "myapp-db-prod"}, {"name": "other-uaa-prod"}
+	]}}`), RawSource)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"MyApp-UAA-Prod", "myapp-uaa-staging"}, env.FindByPrefix("myapp-uaa-"))
+	assert.Equal(t, []string{"MyApp-UAA-Prod", "myapp-db-prod", "myapp-uaa-staging"}, env.FindByPrefix("MYAPP"))
+	assert.Empty(t, env.FindByPrefix("unknown"))
+
+	names, err := env.FindByGlob("myapp-*-prod")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"MyApp-UAA-Prod", "myapp-db-prod"}, names)
+
+	names, err = env.FindByGlob("*-UAA-*")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"MyApp-UAA-Prod", "myapp-uaa-staging", "other-uaa-prod"}, names)
+
+	names, err = env.FindByGlob("nothing-*")
+	assert.NoError(t, err)
+	assert.Empty(t, names)
+
+	_, err = env.FindByGlob("myapp-[")
+	assert.ErrorIs(t, err, path.ErrBadPattern)
+}
+
+func TestFindByRegexp(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"xsuaa": [
+		{"name": "MyApp-UAA-Prod"}, {"name": "myapp-uaa-staging"}, {"name": "myapp-db-prod"}, {"name": "other-uaa-prod"}
+	]}}`), RawSource)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"myapp-db-prod", "myapp-uaa-staging"}, env.FindByRegexp(regexp.MustCompile(`^myapp-(uaa|db)-`)))
+	assert.Equal(t, []string{"MyApp-UAA-Prod", "myapp-db-prod"},
+		env.FindByRegexp(regexp.MustCompile(`(?i)^myapp-(uaa|db)-prod$`)))
+	assert.Empty(t, env.FindByRegexp(regexp.MustCompile(`^nothing`)))
+}
@@ -0,0 +1,207 @@
+package xsenv
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// RedactedValue replaces the values of sensitive keys when redacting.
+const RedactedValue = "***"
+
+// defaultSensitiveKeys are the keys redacted by Redact.
+var defaultSensitiveKeys = []string{"password", "clientsecret", "certificate", "key"}
+
+// Redact returns a copy of msg in which the values of sensitive keys are replaced with RedactedValue,
+// making it safe to log. The keys password, clientsecret, certificate and key are redacted
+// at any depth, compared case-insensitively. Use RedactKeys to configure the keys.
+func Redact(msg *json.RawMessage) (json.RawMessage, error) {
+	return RedactKeys(msg, defaultSensitiveKeys...)
+}
+
+// RedactKeys is like Redact but redacts the values of the given keys.
+func RedactKeys(msg *json.RawMessage, keys ...string) (json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(*msg))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(redactValue(v, keys))
+}
+
+// redactValue replaces the values of keys in v, recursing into objects and arrays.
+func redactValue(v any, keys []string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if isSensitiveKey(key, keys) {
+				v[key] = RedactedValue
+			} else {
+				v[key] = redactValue(value, keys)
+			}
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = redactValue(value, keys)
+		}
+	}
+	return v
+}
+
+// isSensitiveKey reports whether key is one of keys, ignoring case.
+func isSensitiveKey(key string, keys []string) bool {
+	for _, k := range keys {
+		if strings.EqualFold(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redacted returns a copy of v in which the fields tagged with `xsenv:"secret"` are redacted, e.g. to log
+// a decoded configuration safely. Set string fields are replaced with RedactedValue, other secret fields are
+// reset to their zero value. Nested structs are redacted as well, also behind pointers, slices, arrays, maps
+// and interfaces, which are copied so v is left unchanged. Values referenced more than once, also cyclically,
+// are copied once. Unexported fields are copied as is.
+func Redacted(v any) any {
+	if v == nil {
+		return nil
+	}
+	return redactedCopy(reflect.ValueOf(v), make(map[visit]reflect.Value)).Interface()
+}
+
+// visit identifies a pointer, slice or map already copied by redactedCopy.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// redactedCopy returns a copy of v with its secret fields redacted, see Redacted.
+// seen holds the copies of the pointers, slices and maps copied so far, so cycles end.
+func redactedCopy(v reflect.Value, seen map[visit]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		key := visit{ptr: v.Pointer(), typ: v.Type()}
+		if v.Kind() == reflect.Slice {
+			key.len = v.Len()
+		}
+		if copied, ok := seen[key]; ok {
+			return copied
+		}
+		var copied reflect.Value
+		switch v.Kind() {
+		case reflect.Pointer:
+			copied = reflect.New(v.Type().Elem())
+			seen[key] = copied
+			copied.Elem().Set(redactedCopy(v.Elem(), seen))
+		case reflect.Map:
+			copied = reflect.MakeMapWithSize(v.Type(), v.Len())
+			seen[key] = copied
+			for iter := v.MapRange(); iter.Next(); {
+				copied.SetMapIndex(iter.Key(), redactedCopy(iter.Value(), seen))
+			}
+		default:
+			copied = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+			seen[key] = copied
+			for i := 0; i < v.Len(); i++ {
+				copied.Index(i).Set(redactedCopy(v.Index(i), seen))
+			}
+		}
+		return copied
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(redactedCopy(v.Elem(), seen))
+		return copied
+	case reflect.Struct:
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			field := copied.Field(i)
+			if !field.CanSet() {
+				continue
+			}
+			if !parseTag(v.Type().Field(i).Tag.Get(tagName)).has("secret") {
+				field.Set(redactedCopy(field, seen))
+			} else if field.Kind() == reflect.String && field.Len() > 0 {
+				field.SetString(RedactedValue)
+			} else {
+				field.Set(reflect.Zero(field.Type()))
+			}
+		}
+		return copied
+	case reflect.Array:
+		copied := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(redactedCopy(v.Index(i), seen))
+		}
+		return copied
+	}
+	return v
+}
+
+// Pretty returns the service with the given name as indented JSON, e.g. for debugging.
+// It returns ErrServiceNotFound if the service does not exist. Use PrettyRedacted before logging the result.
+func (e *Env) Pretty(name string) (string, error) {
+	msg, ok := e.Raw(name)
+	if !ok {
+		return "", ErrServiceNotFound
+	}
+	return indentJSON(msg)
+}
+
+// PrettyRedacted is like Pretty but redacts the service first, see Redact.
+func (e *Env) PrettyRedacted(name string) (string, error) {
+	msg, ok := e.Raw(name)
+	if !ok {
+		return "", ErrServiceNotFound
+	}
+	redacted, err := Redact(&msg)
+	if err != nil {
+		return "", err
+	}
+	return indentJSON(redacted)
+}
+
+// AsJSON encodes the whole environment in the VCAP_SERVICES shape like Marshal, e.g. to snapshot the
+// effective configuration. If indent is set, the result is indented using two spaces.
+// Use AsRedactedJSON before logging the result.
+func (e *Env) AsJSON(indent bool) ([]byte, error) {
+	data, err := e.Marshal()
+	if err != nil || !indent {
+		return data, err
+	}
+	indented, err := indentJSON(data)
+	return []byte(indented), err
+}
+
+// AsRedactedJSON is like AsJSON but redacts all services first, see Redact.
+func (e *Env) AsRedactedJSON(indent bool) ([]byte, error) {
+	data, err := e.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	msg := json.RawMessage(data)
+	if data, err = Redact(&msg); err != nil || !indent {
+		return data, err
+	}
+	indented, err := indentJSON(data)
+	return []byte(indented), err
+}
+
+// indentJSON indents data using two spaces.
+func indentJSON(data []byte) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
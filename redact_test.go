@@ -0,0 +1,182 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	msg := json.RawMessage(`{
+		"name": "uaa",
+		"credentials": {
+			"clientid": "sb-portal",
+			"clientsecret": "secret",
+			"Password": 1234,
+			"nested": [{"key": {"private": true}, "port": 443}],
+			"certificate": null
+		}
+	}`)
+	redacted, err := Redact(&msg)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"name": "uaa",
+		"credentials": {
+			"clientid": "sb-portal",
+			"clientsecret": "***",
+			"Password": "***",
+			"nested": [{"key": "***", "port": 443}],
+			"certificate": "***"
+		}
+	}`, string(redacted))
+
+	redacted, err = RedactKeys(&msg, "clientid")
+	assert.NoError(t, err)
+	assert.Contains(t, string(redacted), `"clientid":"***"`)
+	assert.Contains(t, string(redacted), `"clientsecret":"secret"`)
+
+	invalid := json.RawMessage(`{invalid`)
+	_, err = Redact(&invalid)
+	assert.Error(t, err)
+}
+
+func TestPretty(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"xsuaa": [
+		{"name": "uaa", "credentials": {"clientid": "sb-uaa", "clientsecret": "secret"}}
+	]}}`), RawSource)
+	assert.NoError(t, err)
+
+	pretty, err := env.Pretty("UAA")
+	assert.NoError(t, err)
+	assert.Equal(t, `{
+  "name": "uaa",
+  "credentials": {
+    "clientid": "sb-uaa",
+    "clientsecret": "secret"
+  }
+}`, pretty)
+
+	redacted, err := env.PrettyRedacted("uaa")
+	assert.NoError(t, err)
+	assert.Contains(t, redacted, `"clientsecret": "`+RedactedValue+`"`)
+	assert.Contains(t, redacted, "\n  ")
+	assert.NotContains(t, redacted, `"secret"`)
+
+	_, err = env.Pretty("nonexistent")
+	assert.ErrorIs(t, err, ErrServiceNotFound)
+	_, err = env.PrettyRedacted("nonexistent")
+	assert.ErrorIs(t, err, ErrServiceNotFound)
+}
+
+func TestAsJSON(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {
+		"xsuaa": [{"name": "uaa", "credentials": {"clientid": "sb-uaa", "clientsecret": "secret"}}],
+		"hana": [{"name": "db", "credentials": {"password": "secret", "port": 30015}}]
+	}}`), RawSource)
+	assert.NoError(t, err)
+
+	data, err := env.AsJSON(false)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "\n")
+	roundTrip, err := loadEnvFromBytes(data, RawSource)
+	assert.NoError(t, err)
+	assert.True(t, roundTrip.Equal(env))
+
+	indented, err := env.AsJSON(true)
+	assert.NoError(t, err)
+	assert.Contains(t, string(indented), "\n  \"VCAP_SERVICES\": {")
+	roundTrip, err = loadEnvFromBytes(indented, RawSource)
+	assert.NoError(t, err)
+	assert.Equal(t, env.Fingerprint(), roundTrip.Fingerprint())
+
+	for _, indent := range []bool{false, true} {
+		redacted, err := env.AsRedactedJSON(indent)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(redacted), `"secret"`)
+		assert.Contains(t, string(redacted), `"sb-uaa"`)
+		assert.Contains(t, string(redacted), "30015")
+		roundTrip, err = loadEnvFromBytes(redacted, RawSource)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"db", "uaa"}, roundTrip.Names())
+	}
+}
+
+type testSecretTLS struct {
+	Cert string `json:"cert"`
+	Key  string `json:"key" xsenv:"secret"`
+}
+
+type testSecretConfig struct {
+	User     string         `json:"user"`
+	Password string         `json:"password" xsenv:"secret"`
+	Empty    string         `json:"empty" xsenv:"secret"`
+	Token    []byte         `json:"token" xsenv:"secret"`
+	TLS      *testSecretTLS `json:"tls"`
+	Replicas []testSecretTLS
+	Extra    any
+	internal string
+}
+
+func TestRedacted(t *testing.T) {
+	config := testSecretConfig{
+		User:     "app",
+		Password: "secret",
+		Token:    []byte("token"),
+		TLS:      &testSecretTLS{Cert: "cert", Key: "private"},
+		Replicas: []testSecretTLS{{Cert: "a", Key: "private"}},
+		Extra:    testSecretTLS{Key: "private"},
+		internal: "kept",
+	}
+	redacted := Redacted(config).(testSecretConfig)
+	assert.Equal(t, testSecretConfig{
+		User:     "app",
+		Password: RedactedValue,
+		TLS:      &testSecretTLS{Cert: "cert", Key: RedactedValue},
+		Replicas: []testSecretTLS{{Cert: "a", Key: RedactedValue}},
+		Extra:    testSecretTLS{Key: RedactedValue},
+		internal: "kept",
+	}, redacted)
+
+	// the original is left unchanged
+	assert.Equal(t, "secret", config.Password)
+	assert.Equal(t, "private", config.TLS.Key)
+	assert.Equal(t, "private", config.Replicas[0].Key)
+
+	pointer := Redacted(&config).(*testSecretConfig)
+	assert.Equal(t, RedactedValue, pointer.Password)
+	assert.NotSame(t, &config, pointer)
+
+	assert.Nil(t, Redacted(nil))
+	assert.Equal(t, "plain", Redacted("plain"))
+	assert.Equal(t, testSecretConfig{}, Redacted(testSecretConfig{}))
+
+	// values of maps are redacted
+	configs := map[string]testSecretTLS{"a": {Cert: "cert", Key: "private"}}
+	assert.Equal(t, map[string]testSecretTLS{"a": {Cert: "cert", Key: RedactedValue}}, Redacted(configs))
+	assert.Equal(t, "private", configs["a"].Key)
+	assert.Equal(t, map[string]any{"a": &testSecretTLS{Key: RedactedValue}},
+		Redacted(map[string]any{"a": &testSecretTLS{Key: "private"}}))
+}
+
+type testSecretNode struct {
+	Secret string `xsenv:"secret"`
+	Next   *testSecretNode
+}
+
+func TestRedactedCycle(t *testing.T) {
+	node := &testSecretNode{Secret: "private"}
+	node.Next = node
+	redacted := Redacted(node).(*testSecretNode)
+	assert.Equal(t, RedactedValue, redacted.Secret)
+	assert.Same(t, redacted, redacted.Next)
+	assert.NotSame(t, node, redacted)
+	assert.Equal(t, "private", node.Secret)
+
+	m := map[string]any{"secret": testSecretNode{Secret: "private"}}
+	m["self"] = m
+	copied := Redacted(m).(map[string]any)
+	assert.Equal(t, RedactedValue, copied["secret"].(testSecretNode).Secret)
+	assert.Equal(t, reflect.ValueOf(copied).Pointer(), reflect.ValueOf(copied["self"]).Pointer())
+}
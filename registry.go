@@ -0,0 +1,54 @@
+package xsenv
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrTypeNotRegistered indicates that no type was registered for a label, see RegisterType.
+var ErrTypeNotRegistered = errors.New("no type registered")
+
+var registry = struct {
+	mu        sync.RWMutex
+	factories map[string]func() UnmarshalService
+}{factories: make(map[string]func() UnmarshalService)}
+
+// RegisterType associates a label with a factory returning a new, empty value to unmarshal services
+// carrying the label into, e.g. RegisterType("xsuaa", func() UnmarshalService { return new(xsuaa.Credentials) }).
+// Registering a label again replaces the previous factory. It is safe for concurrent use
+// and panics if factory is nil.
+func RegisterType(label string, factory func() UnmarshalService) {
+	if factory == nil {
+		panic("xsenv: RegisterType factory is nil")
+	}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.factories[label] = factory
+}
+
+// unregisterType removes the factory registered for label, e.g. to clean up after tests.
+func unregisterType(label string) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	delete(registry.factories, label)
+}
+
+// LoadRegistered loads the only service carrying the given label into a new value of the type
+// registered for the label using RegisterType.
+// It returns ErrTypeNotRegistered if no type was registered for the label
+// and otherwise fails just like LoadSingleByLabel.
+func (e *Env) LoadRegistered(label string) (UnmarshalService, error) {
+	registry.mu.RLock()
+	factory, ok := registry.factories[label]
+	registry.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w for label %q", ErrTypeNotRegistered, label)
+	}
+
+	target := factory()
+	if err := e.LoadSingleByLabel(target, label); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
@@ -0,0 +1,42 @@
+package xsenv
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadRegistered(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {
+		"registry-test": [{"name": "first", "label": "registry-test"}],
+		"registry-twice": [{"name": "a", "label": "registry-twice"}, {"name": "b", "label": "registry-twice"}]
+	}}`), RawSource)
+	assert.NoError(t, err)
+
+	_, err = env.LoadRegistered("registry-test")
+	assert.ErrorIs(t, err, ErrTypeNotRegistered)
+
+	var wg sync.WaitGroup
+	for _, label := range []string{"registry-test", "registry-twice", "registry-missing"} {
+		t.Cleanup(func() { unregisterType(label) })
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			RegisterType(label, func() UnmarshalService { return new(testNamedService) })
+		}()
+	}
+	wg.Wait()
+
+	service, err := env.LoadRegistered("registry-test")
+	assert.NoError(t, err)
+	assert.Equal(t, &testNamedService{Name: "first"}, service)
+
+	_, err = env.LoadRegistered("registry-twice")
+	assert.ErrorIs(t, err, ErrAmbiguousService)
+	_, err = env.LoadRegistered("registry-missing")
+	assert.ErrorIs(t, err, ErrServiceNotFound)
+	assert.ErrorContains(t, err, "label=registry-missing")
+
+	assert.Panics(t, func() { RegisterType("registry-nil", nil) })
+}
@@ -0,0 +1,45 @@
+package xsenv
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrEmptyConfig indicates that the configuration is empty.
+var ErrEmptyConfig = errors.New("configuration is empty")
+
+// LoadEnvFromFileWithRetry loads the environment configuration from a file which may not exist yet,
+// e.g. when the binding file is written shortly after the application started.
+// Reading is attempted up to attempts times, waiting delay in between, as long as the file
+// does not exist or is empty. Other errors, including invalid JSON, are returned immediately.
+// It stops early if ctx is done.
+func LoadEnvFromFileWithRetry(ctx context.Context, fileName string, attempts int, delay time.Duration, opts ...Option) (*Env, error) {
+	o := newOptions(opts)
+	for attempt := 1; ; attempt++ {
+		data, err := o.readFile(fileName)
+		if err == nil && len(bytes.TrimSpace(data)) == 0 {
+			err = fmt.Errorf("%s: %w", fileName, ErrEmptyConfig)
+		}
+		if err == nil {
+			return loadEnvFromBytes(data, FileSource, opts...)
+		}
+		if !errors.Is(err, os.ErrNotExist) && !errors.Is(err, ErrEmptyConfig) {
+			return nil, err
+		}
+		if attempt >= attempts {
+			return nil, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%w: %w", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package xsenv
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadEnvFromFileWithRetry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultEnvFile)
+
+	// the file appears after a few attempts
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		_ = os.WriteFile(path, nil, 0o600)
+		time.Sleep(30 * time.Millisecond)
+		_ = os.WriteFile(path, []byte(`{"VCAP_SERVICES": {"xsuaa": [{"name": "uaa"}]}}`), 0o600)
+	}()
+	env, err := LoadEnvFromFileWithRetry(context.Background(), path, 100, 5*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, FileSource, env.Source)
+	assert.Equal(t, []string{"uaa"}, env.Names())
+}
+
+func TestLoadEnvFromFileWithRetryExhausted(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := LoadEnvFromFileWithRetry(context.Background(), filepath.Join(dir, "missing"), 3, time.Millisecond)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	empty := filepath.Join(dir, "empty")
+	assert.NoError(t, os.WriteFile(empty, []byte(" \n"), 0o600))
+	_, err = LoadEnvFromFileWithRetry(context.Background(), empty, 3, time.Millisecond)
+	assert.ErrorIs(t, err, ErrEmptyConfig)
+}
+
+func TestLoadEnvFromFileWithRetryInvalidJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultEnvFile)
+	assert.NoError(t, os.WriteFile(path, []byte(`{invalid`), 0o600))
+
+	start := time.Now()
+	_, err := LoadEnvFromFileWithRetry(context.Background(), path, 10, time.Second)
+	var syntaxErr *json.SyntaxError
+	assert.ErrorAs(t, err, &syntaxErr)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestLoadEnvFromFileWithRetryCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := LoadEnvFromFileWithRetry(ctx, filepath.Join(t.TempDir(), "missing"), 1000, time.Millisecond)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
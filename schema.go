@@ -0,0 +1,88 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// schemaDialect is the JSON Schema dialect emitted by Schema.
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// Schema generates a minimal JSON Schema describing the struct v, e.g. the credentials a service is expected to have.
+// Property names honor json tags and fields tagged with `xsenv:"required"` are listed as required.
+// Nested structs, pointers, slices, maps and basic types are supported.
+func Schema(v any) ([]byte, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("schema: %T is not a struct", v)
+	}
+	schema := typeSchema(t, make(map[reflect.Type]bool))
+	schema["$schema"] = schemaDialect
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// typeSchema returns the schema of t.
+// visiting holds the struct types currently being described to stop at recursive types.
+func typeSchema(t reflect.Type, visiting map[reflect.Type]bool) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json encodes byte slices as base64 strings
+			return map[string]any{"type": "string"}
+		}
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem(), visiting)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting[t] {
+			return map[string]any{"type": "object"}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		properties := make(map[string]any)
+		required := []string{}
+		for _, f := range structFields(t) {
+			properties[f.name] = typeSchema(f.typ, visiting)
+			if f.opts.has("required") {
+				required = append(required, f.name)
+			}
+		}
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+	// interfaces and other kinds accept any value
+	return map[string]any{}
+}
@@ -0,0 +1,67 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchema(t *testing.T) {
+	type Endpoint struct {
+		Host string `json:"host" xsenv:"required"`
+		Port int    `json:"port"`
+	}
+	type Node struct {
+		Children []*Node `json:"children"`
+	}
+	type Config struct {
+		ClientID string            `json:"clientid" xsenv:"required"`
+		Secret   *string           `json:"secret"`
+		Enabled  bool              `json:"enabled"`
+		Ratio    float64           `json:"ratio"`
+		Scopes   []string          `json:"scopes"`
+		Labels   map[string]string `json:"labels"`
+		Expires  time.Time         `json:"expires"`
+		Cert     []byte            `json:"cert"`
+		Extra    any               `json:"extra"`
+		Primary  Endpoint          `json:"primary" xsenv:"required"`
+		Tree     Node              `json:"tree"`
+		Ignored  string            `json:"-"`
+	}
+
+	data, err := Schema(&Config{})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {
+			"clientid": {"type": "string"},
+			"secret": {"type": "string"},
+			"enabled": {"type": "boolean"},
+			"ratio": {"type": "number"},
+			"scopes": {"type": "array", "items": {"type": "string"}},
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+			"expires": {"type": "string", "format": "date-time"},
+			"cert": {"type": "string"},
+			"extra": {},
+			"primary": {
+				"type": "object",
+				"properties": {"host": {"type": "string"}, "port": {"type": "integer"}},
+				"required": ["host"]
+			},
+			"tree": {
+				"type": "object",
+				"properties": {"children": {"type": "array", "items": {"type": "object"}}}
+			}
+		},
+		"required": ["clientid", "primary"]
+	}`, string(data))
+	assert.True(t, json.Valid(data))
+
+	_, err = Schema("not a struct")
+	assert.Error(t, err)
+	_, err = Schema(nil)
+	assert.Error(t, err)
+}
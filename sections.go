@@ -0,0 +1,59 @@
+package xsenv
+
+import "encoding/json"
+
+// environmentsKey is the credentials field holding per-environment sections, see WithEnvironmentSection.
+const environmentsKey = "environments"
+
+// WithEnvironmentSection selects a per-environment section of the credentials before services are decoded,
+// so a single file can hold variants for e.g. dev, staging and prod:
+//
+//	"credentials": {
+//		"url": "https://default.example.com",
+//		"environments": {"prod": {"url": "https://prod.example.com"}}
+//	}
+//
+// If the credentials hold an environments object with the named section, the fields of the section replace
+// the top-level fields of the same name and the environments object is dropped. Otherwise, the credentials
+// are decoded as they are.
+func WithEnvironmentSection(name string) Option {
+	return func(o *options) {
+		o.environmentSection = name
+	}
+}
+
+// selectSection returns msg with the credentials section name flattened into its credentials.
+// msg is returned unchanged if it does not have such a section.
+func selectSection(msg *json.RawMessage, name string) (*json.RawMessage, error) {
+	var service map[string]json.RawMessage
+	if err := json.Unmarshal(*msg, &service); err != nil {
+		return nil, err
+	}
+	var credentials map[string]json.RawMessage
+	if err := json.Unmarshal(service["credentials"], &credentials); err != nil || credentials == nil {
+		return msg, nil
+	}
+	var sections map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(credentials[environmentsKey], &sections); err != nil {
+		return msg, nil
+	}
+	section, ok := sections[name]
+	if !ok {
+		return msg, nil
+	}
+
+	delete(credentials, environmentsKey)
+	for key, value := range section {
+		credentials[key] = value
+	}
+	data, err := json.Marshal(credentials)
+	if err != nil {
+		return nil, err
+	}
+	service["credentials"] = data
+	if data, err = json.Marshal(service); err != nil {
+		return nil, err
+	}
+	selected := json.RawMessage(data)
+	return &selected, nil
+}
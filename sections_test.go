@@ -0,0 +1,53 @@
+package xsenv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithEnvironmentSection(t *testing.T) {
+	data := []byte(`{"VCAP_SERVICES": {"xsuaa": [
+		{"name": "uaa", "credentials": {
+			"clientid": "sb-uaa",
+			"url": "https://default.example.com",
+			"environments": {
+				"prod": {"url": "https://prod.example.com", "tenant": "prod"},
+				"dev": {"url": "https://dev.example.com"}
+			}
+		}},
+		{"name": "plain", "credentials": {"url": "https://plain.example.com"}},
+		{"name": "invalid", "credentials": {"url": "https://invalid.example.com", "environments": ["prod"]}}
+	]}}`)
+
+	type config struct {
+		ClientID     string         `json:"clientid"`
+		URL          string         `json:"url"`
+		Tenant       string         `json:"tenant"`
+		Environments map[string]any `json:"environments"`
+	}
+
+	env, err := loadEnvFromBytes(data, RawSource, WithEnvironmentSection("prod"))
+	assert.NoError(t, err)
+	cfg, err := Load[config](env, "uaa")
+	assert.NoError(t, err)
+	assert.Equal(t, config{ClientID: "sb-uaa", URL: "https://prod.example.com", Tenant: "prod"}, cfg)
+
+	// services without sections are decoded as they are
+	cfg, err = Load[config](env, "plain")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://plain.example.com", cfg.URL)
+	var invalid struct {
+		URL string `json:"url"`
+	}
+	assert.NoError(t, env.LoadService(&invalid, "invalid"))
+	assert.Equal(t, "https://invalid.example.com", invalid.URL)
+
+	// unknown sections fall back to the top-level credentials
+	env, err = loadEnvFromBytes(data, RawSource, WithEnvironmentSection("staging"))
+	assert.NoError(t, err)
+	cfg, err = Load[config](env, "uaa")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://default.example.com", cfg.URL)
+	assert.Len(t, cfg.Environments, 2)
+}
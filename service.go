@@ -0,0 +1,41 @@
+package xsenv
+
+import "encoding/json"
+
+// Service is the parsed common structure of a service, holding its metadata and raw credentials.
+type Service struct {
+	Name        string
+	Label       string
+	Tags        []string
+	Plan        string
+	BindingName string
+
+	InstanceName string
+	InstanceGUID string
+
+	// Credentials holds the credentials as they are stored, or nil if the service has none.
+	Credentials json.RawMessage
+}
+
+// Service returns the parsed service with the given name, giving typed access to its metadata
+// without decoding the service again. The second return value is false if the service does not exist.
+func (e *Env) Service(name string) (*Service, bool) {
+	key, ok := e.resolve(name)
+	if !ok {
+		return nil, false
+	}
+	meta := e.metaOf(key)
+	service := &Service{
+		Name:         meta.Name,
+		Label:        meta.Label,
+		Tags:         meta.Tags,
+		Plan:         meta.Plan,
+		BindingName:  meta.BindingName,
+		InstanceName: meta.InstanceName,
+		InstanceGUID: meta.InstanceGUID,
+	}
+	if creds, err := e.RawCredentials(key); err == nil {
+		service.Credentials = creds
+	}
+	return service, true
+}
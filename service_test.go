@@ -0,0 +1,39 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvService(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {
+		"xsuaa": [{
+			"name": "uaa", "label": "xsuaa", "plan": "application", "tags": ["xsuaa", "auth"],
+			"binding_name": "portal-uaa", "instance_guid": "guid",
+			"credentials": {"clientid": "client"}
+		}],
+		"user-provided": [{"name": "plain", "credentials": null}]
+	}}`), FileSource)
+	assert.NoError(t, err)
+
+	service, ok := env.Service("UAA")
+	assert.True(t, ok)
+	assert.Equal(t, &Service{
+		Name:         "uaa",
+		Label:        "xsuaa",
+		Tags:         []string{"xsuaa", "auth"},
+		Plan:         "application",
+		BindingName:  "portal-uaa",
+		InstanceGUID: "guid",
+		Credentials:  json.RawMessage(`{"clientid": "client"}`),
+	}, service)
+
+	service, ok = env.Service("plain")
+	assert.True(t, ok)
+	assert.Nil(t, service.Credentials)
+
+	_, ok = env.Service("missing")
+	assert.False(t, ok)
+}
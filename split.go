@@ -0,0 +1,77 @@
+package xsenv
+
+import "sort"
+
+// SplitByLabel partitions the services by their labels, returning one Env per label.
+// Services without a label are collected under the empty label.
+// The environments share the raw messages of the services with e, but are indexed independently,
+// so changing one of them using Set or Remove does not affect the others.
+func (e *Env) SplitByLabel() map[string]*Env {
+	byLabel := make(map[string]map[string]bool)
+	for key := range e.ServicesByName {
+		label := e.metaOf(key).Label
+		if byLabel[label] == nil {
+			byLabel[label] = make(map[string]bool)
+		}
+		byLabel[label][key] = true
+	}
+
+	result := make(map[string]*Env, len(byLabel))
+	for label, keys := range byLabel {
+		result[label] = e.subset(keys)
+	}
+	return result
+}
+
+// subset returns a new Env holding the services of e stored under keys,
+// keeping their groups, sources and metadata.
+func (e *Env) subset(keys map[string]bool) *Env {
+	names := make(map[string]string, len(keys))
+	for key := range keys {
+		names[key] = key
+	}
+	return e.remap(names)
+}
+
+// remap returns a new Env holding the services of e stored under the keys of names, each stored under
+// the name it maps to. Groups, sources and metadata are kept, as well as the order of the services in their groups.
+// The renamed services must not collide.
+func (e *Env) remap(names map[string]string) *Env {
+	o := e.opts
+	if o == nil {
+		o = newOptions(nil)
+	}
+	result := newEnv(e.Source, o)
+
+	groups := make([]string, 0, len(e.groups))
+	for group := range e.groups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	added := make(map[string]bool)
+	addAs := func(group, key string) {
+		name, ok := names[key]
+		msg, exists := e.ServicesByName[key]
+		if !ok || !exists || added[key] {
+			return
+		}
+		added[key] = true
+		source, _ := e.SourceOf(key)
+		meta := e.metaOf(key)
+		if name != key {
+			msg = withName(msg, name)
+			meta.Name = name
+		}
+		result.add(group, name, msg, source, meta)
+	}
+	for _, group := range groups {
+		for _, key := range e.groups[group] {
+			addAs(group, key)
+		}
+	}
+	for key := range names {
+		addAs("", key)
+	}
+	return result
+}
@@ -0,0 +1,38 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitByLabel(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(testPredicateEnv), FileSource)
+	assert.NoError(t, err)
+	msg := json.RawMessage(`{"name": "manual"}`)
+	env.ServicesByName["manual"] = &msg
+
+	split := env.SplitByLabel()
+	assert.Len(t, split, 3)
+
+	uaa := split["xsuaa"]
+	assert.Equal(t, []string{"uaa-app", "uaa-broker"}, uaa.Names())
+	assert.Equal(t, FileSource, uaa.Source)
+	assert.Same(t, env.ServicesByName["uaa-app"], uaa.ServicesByName["uaa-app"])
+	configs, err := LoadGroup[testNamedService](uaa, "xsuaa")
+	assert.NoError(t, err)
+	assert.Len(t, configs, 2)
+
+	assert.Equal(t, []string{"db"}, split["postgresql-db"].Names())
+	assert.Equal(t, []string{"manual"}, split[""].Names())
+
+	// sub-environments are indexed independently
+	assert.True(t, uaa.Remove("UAA-APP"))
+	_, ok := env.Raw("uaa-app")
+	assert.True(t, ok)
+	_, ok = uaa.Raw("db")
+	assert.False(t, ok)
+
+	assert.Empty(t, (&Env{}).SplitByLabel())
+}
@@ -0,0 +1,180 @@
+package xsenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// LoadEnvFromReaderStreaming loads the environment configuration from an io.Reader
+// without buffering the whole input first.
+// The top-level object is tokenized incrementally and every service is decoded on its own,
+// so unusually large configurations are not held in memory twice.
+// The result is the same as the one of LoadEnvFromReader.
+func LoadEnvFromReaderStreaming(reader io.Reader, opts ...Option) (*Env, error) {
+	o := newOptions(opts)
+	reader, err := o.wrapReader(o.limit(reader))
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(o.limitDepth(reader))
+	groups, err := decodeServicesStreaming(dec)
+	if err != nil {
+		return nil, err
+	}
+	// json.Unmarshal rejects anything but whitespace after the top-level value
+	if _, err := dec.Token(); err != io.EOF {
+		if err == nil {
+			err = fmt.Errorf("invalid data after top-level value at offset %d", dec.InputOffset())
+		}
+		return nil, err
+	}
+	return indexServices(groups, RawSource, o)
+}
+
+// decodeServicesStreaming reads the top-level object from dec and returns the
+// service groups found under the EnvironmentKey, ignoring all other keys.
+func decodeServicesStreaming(dec *json.Decoder) (map[string][]*json.RawMessage, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok == nil {
+		// null leaves the configuration empty, just like json.Unmarshal does
+		return nil, nil
+	}
+	if tok == json.Delim('[') {
+		// the services may be given as an array, without the surrounding object
+		services, err := decodeServiceElementsStreaming(dec)
+		if err != nil {
+			return nil, err
+		}
+		return groupByLabel(nil, services), nil
+	}
+	if tok != json.Delim('{') {
+		return nil, invalidConfigToken(tok, "expected an object holding "+EnvironmentKey+" or an array of services")
+	}
+
+	var groups map[string][]*json.RawMessage
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		// encoding/json matches struct fields case-insensitively
+		if !strings.EqualFold(key.(string), EnvironmentKey) {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if groups, err = decodeGroupsStreaming(dec, groups); err != nil {
+			return nil, err
+		}
+	}
+
+	// consume closing brace
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// decodeGroupsStreaming decodes the object holding the service groups into groups.
+// Like json.Unmarshal, repeated groups objects are merged and null resets them.
+// An array of services is grouped by label, see groupByLabel.
+func decodeGroupsStreaming(dec *json.Decoder, groups map[string][]*json.RawMessage) (map[string][]*json.RawMessage, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok == nil {
+		return nil, nil
+	}
+	if tok == json.Delim('[') {
+		services, err := decodeServiceElementsStreaming(dec)
+		if err != nil {
+			return nil, err
+		}
+		return groupByLabel(groups, services), nil
+	}
+	if tok != json.Delim('{') {
+		return nil, invalidConfigToken(tok, "expected "+EnvironmentKey+" to be an object of service groups or an array of services")
+	}
+	if groups == nil {
+		groups = make(map[string][]*json.RawMessage)
+	}
+
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		services, err := decodeServiceArrayStreaming(dec)
+		if err != nil {
+			return nil, err
+		}
+		groups[key.(string)] = services
+	}
+
+	// consume closing brace
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// decodeServiceArrayStreaming decodes a single array of services, one element at a time.
+func decodeServiceArrayStreaming(dec *json.Decoder) ([]*json.RawMessage, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok == nil {
+		return nil, nil
+	}
+	if tok != json.Delim('[') {
+		return nil, unexpectedToken(tok, "array", dec)
+	}
+	return decodeServiceElementsStreaming(dec)
+}
+
+// decodeServiceElementsStreaming decodes the elements of an array of services, one at a time,
+// after its opening bracket was consumed.
+func decodeServiceElementsStreaming(dec *json.Decoder) ([]*json.RawMessage, error) {
+	services := []*json.RawMessage{}
+	for dec.More() {
+		var service *json.RawMessage
+		if err := dec.Decode(&service); err != nil {
+			return nil, err
+		}
+		services = append(services, service)
+	}
+
+	// consume closing bracket
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+// unexpectedToken returns an error describing that tok was found where a value of kind was expected.
+func unexpectedToken(tok json.Token, kind string, dec *json.Decoder) error {
+	return fmt.Errorf("json: cannot unmarshal %v into %s at offset %d", tok, kind, dec.InputOffset())
+}
+
+// invalidConfigToken returns an ErrInvalidConfig error for the scalar tok found where expected describes
+// what was expected instead, like the errors returned by LoadEnvFromReader.
+func invalidConfigToken(tok json.Token, expected string) error {
+	kind := "number"
+	switch tok.(type) {
+	case string:
+		kind = "string"
+	case bool:
+		kind = "boolean"
+	}
+	return fmt.Errorf("%w: %s, got %s", ErrInvalidConfig, expected, kind)
+}
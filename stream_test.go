@@ -0,0 +1,91 @@
+package xsenv
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadEnvFromReaderStreaming(t *testing.T) {
+	testCases := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"single service", `{"VCAP_SERVICES": {"test_service": [{"name": "test"}]}}`, false},
+		{"multiple groups", `{"VCAP_SERVICES": {"a": [{"name": "A1"}, {"name": "a2"}], "b": [{"name": "b1"}]}}`, false},
+		{"other keys", `{"HELLO": "world", "VCAP_SERVICES": {"a": [{"name": "a"}]}, "destinations": [{"name": "x"}]}`, false},
+		{"case-insensitive key", `{"vcap_services": {"a": [{"name": "a"}]}}`, false},
+		{"repeated key", `{"VCAP_SERVICES": {"a": [{"name": "a"}]}, "VCAP_SERVICES": {"b": [{"name": "b"}]}}`, false},
+		{"null services", `{"VCAP_SERVICES": null}`, false},
+		{"null group", `{"VCAP_SERVICES": {"a": null}}`, false},
+		{"null service", `{"VCAP_SERVICES": {"a": [null, {"name": "a"}]}}`, false},
+		{"null document", `null`, false},
+		{"empty object", `{}`, false},
+		{"top-level array", `[]`, false},
+		{"top-level array of services", `[{"name": "uaa", "label": "xsuaa"}, {"name": "plain"}, null]`, false},
+		{"services array", `{"VCAP_SERVICES": [{"name": "uaa", "label": "xsuaa"}]}`, false},
+		{"repeated services array", `{"VCAP_SERVICES": {"a": [{"name": "a"}]}, "VCAP_SERVICES": [{"name": "b"}]}`, false},
+		{"top-level array of numbers", `[1]`, true},
+		{"services not an object", `{"VCAP_SERVICES": 42}`, true},
+		{"group not an array", `{"VCAP_SERVICES": {"a": {"name": "a"}}}`, true},
+		{"invalid name", `{"VCAP_SERVICES": {"a": [{"name": 1}]}}`, true},
+		{"trailing data", `{"VCAP_SERVICES": {}} {}`, true},
+		{"truncated", `{"VCAP_SERVICES": {"a": [`, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expected, expectedErr := LoadEnvFromReader(strings.NewReader(tc.input))
+			env, err := LoadEnvFromReaderStreaming(strings.NewReader(tc.input))
+			if tc.wantErr {
+				assert.Error(t, expectedErr)
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, expectedErr)
+			assert.NoError(t, err)
+			assert.Equal(t, RawSource, env.Source)
+			assert.Equal(t, expected.ServicesByName, env.ServicesByName)
+		})
+	}
+}
+
+// largeEnv returns a VCAP_SERVICES configuration holding n services.
+func largeEnv(n int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`{"VCAP_SERVICES": {"xsuaa": [`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		_, _ = fmt.Fprintf(&buf, `{"name": "service-%d", "label": "xsuaa", "credentials": {"clientid": "sb-%d", "url": "https://example.com/%d"}}`, i, i, i)
+	}
+	buf.WriteString(`]}}`)
+	return buf.Bytes()
+}
+
+func BenchmarkLoadEnvFromReader(b *testing.B) {
+	data := largeEnv(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := LoadEnvFromReader(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoadEnvFromReaderStreaming(b *testing.B) {
+	data := largeEnv(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := LoadEnvFromReaderStreaming(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
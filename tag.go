@@ -0,0 +1,94 @@
+package xsenv
+
+import (
+	"reflect"
+	"strings"
+)
+
+// tagName is the name of the struct tag holding options of this package, e.g. `xsenv:"required"`.
+const tagName = "xsenv"
+
+// tagOptions holds the comma-separated options of an xsenv struct tag.
+// Options of the form "key:value" map key to value, all other options map to an empty string.
+type tagOptions map[string]string
+
+// parseTag parses the options of an xsenv struct tag.
+func parseTag(tag string) tagOptions {
+	opts := make(tagOptions)
+	for _, opt := range strings.Split(tag, ",") {
+		if opt = strings.TrimSpace(opt); opt == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(opt, ":")
+		opts[key] = value
+	}
+	return opts
+}
+
+// has reports whether the option key is set.
+func (t tagOptions) has(key string) bool {
+	_, ok := t[key]
+	return ok
+}
+
+// structField describes an exported struct field as seen by encoding/json.
+type structField struct {
+	// name is the JSON name of the field.
+	name  string
+	index []int
+	typ   reflect.Type
+	opts  tagOptions
+}
+
+// structFields returns the fields of the struct type t which are visible to encoding/json,
+// honoring json tags and flattening embedded structs.
+func structFields(t reflect.Type) []structField {
+	var (
+		fields   []structField
+		embedded []structField
+		seen     = make(map[string]bool)
+	)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		jsonTag := f.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(jsonTag, ",")
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for _, ef := range structFields(ft) {
+					ef.index = append([]int{i}, ef.index...)
+					embedded = append(embedded, ef)
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		seen[name] = true
+		fields = append(fields, structField{
+			name:  name,
+			index: f.Index,
+			typ:   f.Type,
+			opts:  parseTag(f.Tag.Get(tagName)),
+		})
+	}
+	// fields of embedded structs are shadowed by direct fields
+	for _, ef := range embedded {
+		if !seen[ef.name] {
+			seen[ef.name] = true
+			fields = append(fields, ef)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,48 @@
+package xsenv
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTag(t *testing.T) {
+	opts := parseTag("required, index:1,,secret")
+	assert.True(t, opts.has("required"))
+	assert.True(t, opts.has("secret"))
+	assert.False(t, opts.has("group"))
+	assert.Equal(t, "1", opts["index"])
+	assert.Empty(t, parseTag(""))
+}
+
+func TestStructFields(t *testing.T) {
+	type Embedded struct {
+		Shared   string `json:"shared"`
+		Embedded string `json:"embedded"`
+	}
+	type config struct {
+		Embedded
+		Named    string `json:"named" xsenv:"required"`
+		Untagged string
+		Skipped  string `json:"-"`
+		Shared   int    `json:"shared"`
+		private  string
+	}
+	_ = config{}.private
+
+	var names []string
+	for _, f := range structFields(reflect.TypeOf(config{})) {
+		names = append(names, f.name)
+		if f.name == "shared" {
+			assert.Equal(t, reflect.TypeOf(0), f.typ)
+		}
+		if f.name == "named" {
+			assert.True(t, f.opts.has("required"))
+		}
+		if f.name == "embedded" {
+			assert.Equal(t, []int{0, 1}, f.index)
+		}
+	}
+	assert.Equal(t, []string{"named", "Untagged", "shared", "embedded"}, names)
+}
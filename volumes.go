@@ -0,0 +1,37 @@
+package xsenv
+
+import "encoding/json"
+
+// VolumeMount describes a volume of a service binding, as defined by the Open Service Broker API.
+type VolumeMount struct {
+	Driver       string       `json:"driver"`
+	ContainerDir string       `json:"container_dir"`
+	Mode         string       `json:"mode"`
+	DeviceType   string       `json:"device_type"`
+	Device       VolumeDevice `json:"device"`
+}
+
+// VolumeDevice describes the device of a VolumeMount.
+type VolumeDevice struct {
+	VolumeID    string         `json:"volume_id"`
+	MountConfig map[string]any `json:"mount_config"`
+}
+
+// VolumeMounts returns the volume mounts of the service with the given name.
+// It returns an empty slice if the service has no mounts and ErrServiceNotFound if it does not exist.
+func (e *Env) VolumeMounts(name string) ([]VolumeMount, error) {
+	key, ok := e.resolve(name)
+	if !ok {
+		return nil, ErrServiceNotFound
+	}
+	parsed := struct {
+		VolumeMounts []VolumeMount `json:"volume_mounts"`
+	}{}
+	if err := json.Unmarshal(*e.ServicesByName[key], &parsed); err != nil {
+		return nil, err
+	}
+	if parsed.VolumeMounts == nil {
+		return []VolumeMount{}, nil
+	}
+	return parsed.VolumeMounts, nil
+}
@@ -0,0 +1,47 @@
+package xsenv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVolumeMounts(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {
+		"nfs": [{
+			"name": "files",
+			"volume_mounts": [{
+				"driver": "nfsv3driver",
+				"container_dir": "/var/vcap/data/files",
+				"mode": "rw",
+				"device_type": "shared",
+				"device": {"volume_id": "abc", "mount_config": {"uid": "1000"}}
+			}]
+		}],
+		"xsuaa": [{"name": "uaa", "volume_mounts": []}, {"name": "other"}],
+		"broken": [{"name": "broken", "volume_mounts": {}}]
+	}}`), RawSource)
+	assert.NoError(t, err)
+
+	mounts, err := env.VolumeMounts("files")
+	assert.NoError(t, err)
+	assert.Equal(t, []VolumeMount{{
+		Driver:       "nfsv3driver",
+		ContainerDir: "/var/vcap/data/files",
+		Mode:         "rw",
+		DeviceType:   "shared",
+		Device:       VolumeDevice{VolumeID: "abc", MountConfig: map[string]any{"uid": "1000"}},
+	}}, mounts)
+
+	for _, name := range []string{"uaa", "other"} {
+		mounts, err = env.VolumeMounts(name)
+		assert.NoError(t, err)
+		assert.NotNil(t, mounts)
+		assert.Empty(t, mounts)
+	}
+
+	_, err = env.VolumeMounts("broken")
+	assert.Error(t, err)
+	_, err = env.VolumeMounts("nonexistent")
+	assert.ErrorIs(t, err, ErrServiceNotFound)
+}
@@ -0,0 +1,53 @@
+package xsenv
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultPollInterval is the interval WaitForService reloads the configuration at, unless set using WithPollInterval.
+const DefaultPollInterval = time.Second
+
+// WithPollInterval sets the interval WaitForService reloads the configuration at. It defaults to DefaultPollInterval.
+func WithPollInterval(d time.Duration) CallOption {
+	return func(o *callOptions) {
+		if d > 0 {
+			o.pollInterval = d
+		}
+	}
+}
+
+// WaitForService waits until the service with the given name is present, for platforms on which
+// bindings appear after the application started. If e lacks the service, the configuration is reloaded
+// using reload every poll interval (see WithPollInterval) until the service appears, in which case
+// e is replaced by the reloaded configuration. Errors returned by reload are retried as well.
+// If ctx is done first, the returned error wraps ctx.Err() together with ErrServiceNotFound
+// or the last error of reload.
+func (e *Env) WaitForService(ctx context.Context, name string, reload func() (*Env, error), opts ...CallOption) error {
+	if _, ok := e.resolve(name); ok {
+		return nil
+	}
+	o := newCallOptions(opts)
+	ticker := time.NewTicker(o.pollInterval)
+	defer ticker.Stop()
+
+	var lastErr error = ErrServiceNotFound
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %s: %w", ctx.Err(), name, lastErr)
+		case <-ticker.C:
+		}
+		env, err := reload()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		lastErr = ErrServiceNotFound
+		if _, ok := env.resolve(name); ok {
+			*e = *env
+			return nil
+		}
+	}
+}
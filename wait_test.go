@@ -0,0 +1,59 @@
+package xsenv
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForService(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"a": [{"name": "present"}]}}`), RawSource)
+	assert.NoError(t, err)
+
+	// present services do not reload
+	assert.NoError(t, env.WaitForService(context.Background(), "present", func() (*Env, error) {
+		t.Fatal("unexpected reload")
+		return nil, nil
+	}))
+
+	calls := 0
+	reload := func() (*Env, error) {
+		calls++
+		switch calls {
+		case 1:
+			return nil, errors.New("not readable yet")
+		case 2:
+			return loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"a": [{"name": "present"}]}}`), RawSource)
+		}
+		return loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"a": [{"name": "present"}, {"name": "late"}]}}`), FileSource)
+	}
+	assert.NoError(t, env.WaitForService(context.Background(), "late", reload, WithPollInterval(time.Millisecond)))
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []string{"late", "present"}, env.Names())
+	assert.Equal(t, FileSource, env.Source)
+}
+
+func TestWaitForServiceCanceled(t *testing.T) {
+	env := &Env{}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := env.WaitForService(ctx, "never", func() (*Env, error) {
+		return &Env{}, nil
+	}, WithPollInterval(time.Millisecond))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, err, ErrServiceNotFound)
+	assert.ErrorContains(t, err, "never")
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	reloadErr := errors.New("broken")
+	err = env.WaitForService(ctx, "never", func() (*Env, error) {
+		return nil, reloadErr
+	}, WithPollInterval(time.Millisecond))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, err, reloadErr)
+}
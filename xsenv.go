@@ -3,11 +3,14 @@
 package xsenv
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"sort"
 	"strings"
 )
 
@@ -17,8 +20,25 @@ const (
 	FileSource        Source = "file"
 	EnvironmentSource Source = "environment"
 	RawSource         Source = "raw"
+	MergedSource      Source = "merged"
+	KubernetesSource  Source = "kubernetes"
+	// NoneSource is the source of an empty Env returned when no configuration was found, see WithAllowMissing.
+	NoneSource Source = "none"
 )
 
+// sources are all known sources.
+var sources = []Source{FileSource, EnvironmentSource, RawSource, MergedSource, KubernetesSource, NoneSource}
+
+// Valid reports whether s is one of the known sources.
+func (s Source) Valid() bool {
+	return slices.Contains(sources, s)
+}
+
+// String returns the name of the source, e.g. "file".
+func (s Source) String() string {
+	return string(s)
+}
+
 const (
 	// DefaultEnvFile is the default filename for the environment configuration.
 	DefaultEnvFile = "default-env.json"
@@ -29,82 +49,692 @@ const (
 
 // ErrServiceNotFound indicates that the requested service was not found in the environment configuration.
 var (
-	ErrServiceNotFound = errors.New("service not found")
-	ErrFieldMissing    = errors.New("field(s) missing")
+	ErrServiceNotFound  = errors.New("service not found")
+	ErrFieldMissing     = errors.New("field(s) missing")
+	ErrEmptyServiceName = errors.New("service without name")
+	ErrDuplicateService = errors.New("duplicate service name")
+	ErrTooFewServices   = errors.New("too few services")
+	// ErrInvalidSource indicates that a source is not one of the known sources, see Source.Valid.
+	ErrInvalidSource = errors.New("invalid source")
+	// ErrInvalidConfig indicates that the configuration is valid JSON but has an unexpected shape,
+	// e.g. a bare string instead of an object.
+	ErrInvalidConfig = errors.New("invalid configuration")
 )
 
 // LoadEnv loads the environment configuration from environment variables if available, otherwise from the default file.
+// Using WithMergeSources, both sources are loaded and merged.
+// Using WithAllowMissing, an empty Env with NoneSource is returned if neither source is present.
+// Besides the object holding VCAP_SERVICES, the configuration may be an array of services as set by some tools,
+// in which case services are grouped by their label.
 // It returns an Env instance on success or an error if loading fails.
-func LoadEnv() (*Env, error) {
-	env, ok := os.LookupEnv(EnvironmentKey)
+func LoadEnv(opts ...Option) (*Env, error) {
+	o := newOptions(opts)
+	if o.mergeSources {
+		return loadEnvMerged(o, opts)
+	}
+	env, ok := os.LookupEnv(o.envKey)
 	if ok {
-		return loadEnvFromBytes([]byte(env), EnvironmentSource)
+		return loadEnvFromBytes([]byte(env), EnvironmentSource, opts...)
 	}
-	return LoadEnvFromFile(DefaultEnvFile)
+	loaded, err := LoadEnvFromFile(DefaultEnvFile, opts...)
+	if err != nil && o.allowMissing && errors.Is(err, os.ErrNotExist) {
+		return emptyEnv(o)
+	}
+	return loaded, err
+}
+
+// emptyEnv returns an empty Env with NoneSource, as long as it satisfies the requirements of o.
+func emptyEnv(o *options) (*Env, error) {
+	env := newEnv(NoneSource, o)
+	if err := o.validate(env); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// loadEnvMerged loads both the environment variable and the default file, if present, and merges them.
+// Services from the environment variable take precedence over services from the file.
+func loadEnvMerged(o *options, opts []Option) (*Env, error) {
+	// requirements are checked on the merged result only
+	sourceOpts := append(opts[:len(opts):len(opts)], WithMinServices(0))
+
+	var fromEnv *Env
+	if data, ok := os.LookupEnv(o.envKey); ok {
+		env, err := loadEnvFromBytes([]byte(data), EnvironmentSource, sourceOpts...)
+		if err != nil {
+			return nil, err
+		}
+		fromEnv = env
+	}
+
+	fromFile, err := LoadEnvFromFile(DefaultEnvFile, sourceOpts...)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		// a missing file is fine as long as the environment variable is set
+		if fromEnv == nil {
+			if o.allowMissing {
+				return emptyEnv(o)
+			}
+			return nil, err
+		}
+	}
+
+	env := fromEnv
+	if fromFile != nil {
+		env = fromFile
+		if fromEnv != nil {
+			env = fromFile.Merge(fromEnv)
+		}
+	}
+	if err := o.validate(env); err != nil {
+		return nil, err
+	}
+	return env, nil
 }
 
 // LoadEnvFromReader loads the environment configuration from an io.Reader.
 // It returns an Env instance on success or an error if loading fails.
-func LoadEnvFromReader(reader io.Reader) (*Env, error) {
-	data, err := io.ReadAll(reader)
+func LoadEnvFromReader(reader io.Reader, opts ...Option) (*Env, error) {
+	data, err := io.ReadAll(newOptions(opts).limit(reader))
 	if err != nil {
 		return nil, err
 	}
-	return loadEnvFromBytes(data, RawSource)
+	return loadEnvFromBytes(data, RawSource, opts...)
 }
 
 // LoadEnvFromFile loads the environment configuration from a specified file.
 // It returns an Env instance on success or an error if loading fails.
-func LoadEnvFromFile(fileName string) (*Env, error) {
-	data, err := os.ReadFile(fileName)
+func LoadEnvFromFile(fileName string, opts ...Option) (*Env, error) {
+	data, err := newOptions(opts).readFile(fileName)
 	if err != nil {
 		return nil, err
 	}
-	return loadEnvFromBytes(data, FileSource)
+	return loadEnvFromBytes(data, FileSource, opts...)
+}
+
+// LoadEnvFromFiles loads the environment configuration from each of the files in order and merges them,
+// so services of later files take precedence over services with the same name of earlier files,
+// e.g. to layer environment-specific overrides on top of a base file.
+// Missing files fail loading unless WithOptionalFiles is set. Requirements such as WithMinServices
+// are checked on the merged result.
+// The paths are passed as a slice rather than variadically, so options can be passed along,
+// e.g. LoadEnvFromFiles([]string{"base.json", "dev.json"}, WithOptionalFiles()).
+func LoadEnvFromFiles(paths []string, opts ...Option) (*Env, error) {
+	o := newOptions(opts)
+	fileOpts := append(opts[:len(opts):len(opts)], WithMinServices(0))
+
+	env := newEnv(FileSource, o)
+	for _, path := range paths {
+		loaded, err := LoadEnvFromFile(path, fileOpts...)
+		if err != nil {
+			if o.optionalFiles && errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		env = env.Merge(loaded)
+	}
+	if err := o.validate(env); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// FromServices builds an environment configuration from Go values, mapping service names to their credentials.
+// Every value is marshaled into the credentials of a service in the "user-provided" group.
+// It returns ErrDuplicateService if two names collide after normalization.
+func FromServices(services map[string]any, opts ...Option) (*Env, error) {
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	env := newEnv(RawSource, newOptions(opts))
+	for _, name := range names {
+		if key, ok := env.indexed(name); ok {
+			return nil, fmt.Errorf("%w: %s collides with %s", ErrDuplicateService, name, key)
+		}
+		creds, err := json.Marshal(services[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		env.addCredentials(name, creds)
+	}
+	return env, nil
 }
 
 // loadEnvFromBytes is an internal function that loads environment configuration
 // from a byte slice. It is used by LoadEnvFromReader and LoadEnvFromFile.
-func loadEnvFromBytes(data []byte, source Source) (*Env, error) {
+func loadEnvFromBytes(data []byte, source Source, opts ...Option) (*Env, error) {
+	o := newOptions(opts)
+	if err := o.checkSize(data); err != nil {
+		return nil, err
+	}
+	data, err := o.decodeData(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.checkDepth(data); err != nil {
+		return nil, err
+	}
+
+	if kind := jsonKind(data); kind != "object" && kind != "array" && kind != "null" && json.Valid(data) {
+		return nil, fmt.Errorf("%w: expected an object holding %s or an array of services, got %s",
+			ErrInvalidConfig, EnvironmentKey, kind)
+	}
+	if isJSONArray(data) {
+		// the services may be given as an array, without the surrounding object
+		var groups serviceGroups
+		if err := json.Unmarshal(data, &groups); err != nil {
+			return nil, err
+		}
+		return indexServices(groups, source, o)
+	}
 	parseEnv := struct {
-		Services map[string][]*json.RawMessage `json:"VCAP_SERVICES"`
+		Services serviceGroups `json:"VCAP_SERVICES"`
 	}{}
 	if err := json.Unmarshal(data, &parseEnv); err != nil {
 		return nil, err
 	}
+	return indexServices(parseEnv.Services, source, o)
+}
+
+// serviceGroups holds the services of VCAP_SERVICES by group. Besides the object of groups, it accepts
+// an array of services as set by some tools, grouping the services by their label, see groupByLabel.
+type serviceGroups map[string][]*json.RawMessage
+
+func (g *serviceGroups) UnmarshalJSON(data []byte) error {
+	if kind := jsonKind(data); kind != "object" && kind != "array" && kind != "null" {
+		return fmt.Errorf("%w: expected %s to be an object of service groups or an array of services, got %s",
+			ErrInvalidConfig, EnvironmentKey, kind)
+	}
+	if !isJSONArray(data) {
+		return json.Unmarshal(data, (*map[string][]*json.RawMessage)(g))
+	}
+	var services []*json.RawMessage
+	if err := json.Unmarshal(data, &services); err != nil {
+		return err
+	}
+	*g = groupByLabel(*g, services)
+	return nil
+}
+
+// groupByLabel adds services to groups, grouping them by their label.
+// Services without a label are put into the "user-provided" group.
+func groupByLabel(groups map[string][]*json.RawMessage, services []*json.RawMessage) map[string][]*json.RawMessage {
+	if groups == nil {
+		groups = make(map[string][]*json.RawMessage)
+	}
+	for _, service := range services {
+		group := defaultGroup
+		var parsed struct {
+			Label string `json:"label"`
+		}
+		// invalid services are reported when indexing them
+		if service != nil && json.Unmarshal(*service, &parsed) == nil && parsed.Label != "" {
+			group = parsed.Label
+		}
+		groups[group] = append(groups[group], service)
+	}
+	return groups
+}
+
+// isJSONArray reports whether data holds a JSON array, ignoring leading whitespace.
+func isJSONArray(data []byte) bool {
+	return jsonKind(data) == "array"
+}
 
-	type parseName struct {
-		Name string `json:"name"`
+// jsonKind returns the kind of the JSON value in data, like "object" or "number", judging by its first character.
+// It returns an empty string if data does not start like a JSON value.
+func jsonKind(data []byte) string {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if len(trimmed) == 0 {
+		return ""
+	}
+	switch c := trimmed[0]; {
+	case c == '{':
+		return "object"
+	case c == '[':
+		return "array"
+	case c == '"':
+		return "string"
+	case c == 't' || c == 'f':
+		return "boolean"
+	case c == 'n':
+		return "null"
+	case c == '-' || c >= '0' && c <= '9':
+		return "number"
 	}
-	m := make(map[string]*json.RawMessage)
-	for _, services := range parseEnv.Services {
-		for _, service := range services {
-			var name parseName
-			if err := json.Unmarshal(*service, &name); err != nil {
-				return nil, err
+	return ""
+}
+
+// LoadServicesMap loads the environment configuration from data holding the services map only,
+// i.e. the value of VCAP_SERVICES without the surrounding object, like {"xsuaa": [{...}]}.
+// It returns an Env instance on success or an error if loading fails.
+func LoadServicesMap(data []byte, opts ...Option) (*Env, error) {
+	o := newOptions(opts)
+	if err := o.checkSize(data); err != nil {
+		return nil, err
+	}
+	if err := o.checkDepth(data); err != nil {
+		return nil, err
+	}
+	var groups map[string][]*json.RawMessage
+	if err := json.Unmarshal(data, &groups); err != nil {
+		return nil, err
+	}
+	return indexServices(groups, RawSource, o)
+}
+
+// indexServices builds an Env from the service groups of a VCAP_SERVICES object,
+// indexing every service by its normalized name.
+func indexServices(groups map[string][]*json.RawMessage, source Source, o *options) (*Env, error) {
+	env := newEnv(source, o)
+	// groups are indexed in sorted order, so the handling of duplicate names is deterministic
+	groupNames := make([]string, 0, len(groups))
+	for group := range groups {
+		groupNames = append(groupNames, group)
+	}
+	sort.Strings(groupNames)
+	for _, group := range groupNames {
+		for _, service := range groups[group] {
+			if service == nil {
+				o.warn("skipping null service", "group", group)
+				continue
+			}
+			var parsed serviceMeta
+			if err := json.Unmarshal(*service, &parsed); err != nil {
+				if !o.skipInvalid {
+					return nil, err
+				}
+				o.warn("skipping invalid service", "group", group, "error", err)
+				env.skipped = append(env.skipped, fmt.Errorf("group %s: %w", group, err))
+				continue
 			}
-			m[strings.ToLower(name.Name)] = service
+			name := parsed.Name
+			if name == "" {
+				switch o.emptyNames {
+				case RejectEmptyNames:
+					return nil, fmt.Errorf("%w in group %s", ErrEmptyServiceName, group)
+				case FallbackEmptyNames:
+					name = parsed.BindingName
+					if name == "" {
+						name = parsed.Label
+					}
+				}
+			}
+			if name == "" {
+				o.warn("skipping service without name", "group", group)
+				continue
+			}
+			if stripped := o.stripPrefix(name); stripped != name {
+				name = stripped
+				if parsed.Name != "" {
+					parsed.Name = stripped
+				}
+			}
+			if o.rawTransformer != nil {
+				transformed, err := o.rawTransformer(name, *service)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+				// the metadata may have been changed as well, but the name stays the one extracted before
+				metaName := parsed.Name
+				parsed = serviceMeta{}
+				if err := json.Unmarshal(transformed, &parsed); err != nil {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+				parsed.Name = metaName
+				service = &transformed
+			}
+			if len(o.fileReferences) > 0 {
+				resolved, err := o.resolveFileReferences(*service)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+				service = &resolved
+			}
+			if existing, ok := env.indexed(name); ok {
+				switch o.duplicates {
+				case FirstWins:
+					o.warn("skipping duplicate service", "group", group, "name", name)
+					continue
+				case RejectDuplicates:
+					return nil, fmt.Errorf("%w: %s in group %s collides with %s", ErrDuplicateService, name, group, existing)
+				}
+				o.warn("replacing duplicate service", "group", group, "name", name)
+			}
+			env.add(group, name, service, source, parsed)
 		}
 	}
+	if o.serviceOverrides {
+		if err := applyServiceOverrides(env); err != nil {
+			return nil, err
+		}
+	}
+	if err := o.validate(env); err != nil {
+		return nil, err
+	}
+	o.metrics.IncLoad(source)
+	return env, nil
+}
 
-	return &Env{source, m}, nil
+// newEnv returns an empty Env for the given source and options.
+func newEnv(source Source, o *options) *Env {
+	env := &Env{
+		Source:         source,
+		ServicesByName: make(map[string]*json.RawMessage),
+		ServiceSources: make(map[string]Source),
+		groups:         make(map[string][]string),
+		meta:           make(map[string]serviceMeta),
+		index:          make(map[string]string),
+		opts:           o,
+	}
+	if o.decodeCache {
+		env.cache = &decodeCache{}
+	}
+	return env
 }
 
 // Env represents the environment configuration, holding service configurations by name.
 type Env struct {
 	Source Source
-	// ServicesByName maps service names to their JSON configuration.
+	// ServicesByName maps service names, in their original casing, to their JSON configuration.
+	// Use LoadService or Raw for lookups which apply the name normalization.
 	ServicesByName map[string]*json.RawMessage
+	// ServiceSources maps service names to the source they were loaded from.
+	// It is preserved when merging environments.
+	ServiceSources map[string]Source
+
+	// groups maps the VCAP_SERVICES groups (e.g. "xsuaa") to the keys of their services.
+	groups map[string][]string
+	// meta holds the metadata parsed while indexing, keyed like ServicesByName.
+	meta map[string]serviceMeta
+	// index maps normalized service names to their keys in ServicesByName.
+	index map[string]string
+	// cache holds decoded values if enabled using WithDecodeCache, see Get.
+	cache *decodeCache
+	// skipped holds the errors of services skipped using WithSkipInvalid, see Skipped.
+	skipped []error
+	opts    *options
+}
+
+// serviceMeta holds the common metadata fields of a service.
+type serviceMeta struct {
+	Name        string   `json:"name"`
+	BindingName string   `json:"binding_name"`
+	Label       string   `json:"label"`
+	Tags        []string `json:"tags"`
+	Plan        string   `json:"plan"`
+
+	InstanceName string `json:"instance_name"`
+	InstanceGUID string `json:"instance_guid"`
+}
+
+// UnmarshalJSON decodes the metadata of a service. Only the name must be a string, other fields holding
+// another type are left empty, so the metadata parsed for indexing never rejects a configuration.
+func (m *serviceMeta) UnmarshalJSON(data []byte) error {
+	// most services hold the expected types, so they are decoded at once
+	type plain serviceMeta
+	if err := json.Unmarshal(data, (*plain)(m)); err == nil {
+		return nil
+	}
+	var fields struct {
+		Name        string          `json:"name"`
+		BindingName json.RawMessage `json:"binding_name"`
+		Label       json.RawMessage `json:"label"`
+		Tags        json.RawMessage `json:"tags"`
+		Plan        json.RawMessage `json:"plan"`
+
+		InstanceName json.RawMessage `json:"instance_name"`
+		InstanceGUID json.RawMessage `json:"instance_guid"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*m = serviceMeta{
+		Name:         fields.Name,
+		BindingName:  lenient[string](fields.BindingName),
+		Label:        lenient[string](fields.Label),
+		Tags:         lenient[[]string](fields.Tags),
+		Plan:         lenient[string](fields.Plan),
+		InstanceName: lenient[string](fields.InstanceName),
+		InstanceGUID: lenient[string](fields.InstanceGUID),
+	}
+	return nil
+}
+
+// lenient decodes raw into a T, returning the zero value if raw is absent or holds another type.
+func lenient[T any](raw json.RawMessage) T {
+	var v T
+	if err := json.Unmarshal(raw, &v); err != nil {
+		var zero T
+		return zero
+	}
+	return v
+}
+
+// metaOf returns the metadata of the service stored under key.
+// Services without indexed metadata, e.g. added to ServicesByName by hand, are parsed on demand.
+func (e *Env) metaOf(key string) serviceMeta {
+	if meta, ok := e.meta[key]; ok {
+		return meta
+	}
+	var meta serviceMeta
+	if msg := e.ServicesByName[key]; msg != nil {
+		_ = json.Unmarshal(*msg, &meta)
+	}
+	return meta
+}
+
+// add stores msg, loaded from source, under key as a member of group.
+// A service previously stored under a key with the same normalized name is replaced, callers handle
+// services added to ServicesByName by hand using lookup.
+// An empty group leaves the service ungrouped.
+func (e *Env) add(group, key string, msg *json.RawMessage, source Source, meta serviceMeta) {
+	if e.ServicesByName == nil {
+		e.ServicesByName = make(map[string]*json.RawMessage)
+	}
+	if e.ServiceSources == nil {
+		e.ServiceSources = make(map[string]Source)
+	}
+	if e.groups == nil {
+		e.groups = make(map[string][]string)
+	}
+	if e.meta == nil {
+		e.meta = make(map[string]serviceMeta)
+	}
+	if e.index == nil {
+		e.index = make(map[string]string)
+	}
+	if existing, ok := e.indexed(key); ok {
+		e.delete(existing)
+	}
+	e.ServicesByName[key] = msg
+	e.ServiceSources[key] = source
+	e.meta[key] = meta
+	e.index[e.normalize(key)] = key
+	if group != "" {
+		e.groups[group] = append(e.groups[group], key)
+	}
+}
+
+// delete removes the service stored under key.
+func (e *Env) delete(key string) {
+	delete(e.ServicesByName, key)
+	delete(e.ServiceSources, key)
+	delete(e.meta, key)
+	e.cache.invalidate(key)
+	if normalized := e.normalize(key); e.index[normalized] == key {
+		delete(e.index, normalized)
+	}
+	e.removeFromGroups(key)
+}
+
+// lookup returns the key in ServicesByName of the service with the given name.
+// Names are compared after normalization.
+func (e *Env) lookup(name string) (string, bool) {
+	if key, ok := e.indexed(name); ok {
+		return key, true
+	}
+	// services added to ServicesByName by hand are not indexed
+	normalized := e.normalize(name)
+	for key := range e.ServicesByName {
+		if e.normalize(key) == normalized {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// indexed is like lookup, but only consults the index, so it does not find services added to ServicesByName by hand.
+// It is used while building an Env, where every service is indexed, to keep loading linear in the number of services.
+func (e *Env) indexed(name string) (string, bool) {
+	key, ok := e.index[e.normalize(name)]
+	if !ok {
+		return "", false
+	}
+	if _, ok := e.ServicesByName[key]; !ok {
+		return "", false
+	}
+	return key, true
+}
+
+// Skipped returns the errors of the services which were skipped while loading because they could not be parsed,
+// see WithSkipInvalid. It returns nil if no service was skipped.
+func (e *Env) Skipped() []error {
+	return e.skipped
+}
+
+// Names returns the names of all services in their original casing, sorted.
+func (e *Env) Names() []string {
+	return e.sortedKeys()
 }
 
-// LoadService loads a service configuration by name into a UnmarshalService.
+// sortedKeys returns the keys of all services in sorted order.
+func (e *Env) sortedKeys() []string {
+	keys := make([]string, 0, len(e.ServicesByName))
+	for key := range e.ServicesByName {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// removeFromGroups removes key from the group it belongs to, dropping the group once it is empty.
+func (e *Env) removeFromGroups(key string) {
+	for group, keys := range e.groups {
+		for i, k := range keys {
+			if k != key {
+				continue
+			}
+			keys = append(keys[:i:i], keys[i+1:]...)
+			if len(keys) == 0 {
+				delete(e.groups, group)
+			} else {
+				e.groups[group] = keys
+			}
+			return
+		}
+	}
+}
+
+// normalize applies the configured name normalizer to name.
+// An Env that was not created by one of the loaders falls back to DefaultNormalizer.
+func (e *Env) normalize(name string) string {
+	if e.opts == nil {
+		return DefaultNormalizer(name)
+	}
+	return e.opts.normalize(e.opts.stripPrefix(name))
+}
+
+// Len returns the number of services.
+func (e *Env) Len() int {
+	return len(e.ServicesByName)
+}
+
+// IsEmpty reports whether e holds no services.
+func (e *Env) IsEmpty() bool {
+	return e.Len() == 0
+}
+
+// RangeGroup calls fn for every service of a VCAP_SERVICES group (e.g. "rabbitmq"), in the order of the configuration,
+// with the name and raw configuration of the service. Iteration stops when fn returns false.
+func (e *Env) RangeGroup(group string, fn func(name string, msg *json.RawMessage) bool) {
+	for _, key := range e.groups[group] {
+		msg, ok := e.ServicesByName[key]
+		if !ok {
+			continue
+		}
+		if !fn(key, msg) {
+			return
+		}
+	}
+}
+
+// LoadService loads a service configuration by name into target.
+// If target implements UnmarshalService, it is used to unmarshal the service. Otherwise, the credentials of the service,
+// or the whole service if it has no credentials, are passed to UnmarshalCredentials if target implements it,
+// or decoded into target, which must be a pointer then.
 // It returns an error if the service cannot be found or the unmarshaling fails.
-func (e *Env) LoadService(target UnmarshalService, name string) error {
-	msg, ok := e.ServicesByName[strings.ToLower(name)]
+func (e *Env) LoadService(target any, name string) error {
+	key, ok := e.resolve(name)
 	if !ok {
+		e.metrics().IncNotFound(name)
 		return ErrServiceNotFound
 	}
-	return target.UnmarshalService(msg)
+	return e.decode(target, key)
+}
+
+// SourceOf returns the source the service with the given name was loaded from.
+// The second return value is false if the service does not exist.
+func (e *Env) SourceOf(name string) (Source, bool) {
+	key, ok := e.resolve(name)
+	if !ok {
+		return "", false
+	}
+	if source, ok := e.ServiceSources[key]; ok {
+		return source, true
+	}
+	return e.Source, true
+}
+
+// Raw returns the raw JSON configuration of the service with the given name.
+// The second return value is false if the service does not exist.
+func (e *Env) Raw(name string) (json.RawMessage, bool) {
+	key, ok := e.resolve(name)
+	if !ok {
+		return nil, false
+	}
+	return *e.ServicesByName[key], true
+}
+
+// LoadServices loads multiple service configurations, mapping service names to their targets.
+// Every target is attempted; the returned error joins the failures of all services,
+// each prefixed with the name of the service that failed.
+// Using WithFailFast, it stops at the first failure instead. Services are loaded in sorted order of their names.
+func (e *Env) LoadServices(targets map[string]UnmarshalService, opts ...CallOption) error {
+	o := newCallOptions(opts)
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		if err := e.LoadService(targets[name], name); err != nil {
+			err = fmt.Errorf("%s: %w", name, err)
+			if o.failFast {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // UnmarshalService is an interface for types that can unmarshal
@@ -113,6 +743,13 @@ type UnmarshalService interface {
 	UnmarshalService(*json.RawMessage) error
 }
 
+// UnmarshalCredentials is an alternative to UnmarshalService for types only interested in the credentials
+// of a service. It is passed the credentials of the service, or the whole service if it has no credentials.
+// UnmarshalService takes precedence for types implementing both.
+type UnmarshalCredentials interface {
+	UnmarshalCredentials(*json.RawMessage) error
+}
+
 // MissingFieldError returns an error indicating that a field is missing.
 // This is useful when implementing UnmarshalService.
 func MissingFieldError(field string) error {
@@ -121,15 +758,25 @@ func MissingFieldError(field string) error {
 
 type Fields = map[string]bool
 
-// CheckAllFields checks if all fields in a map are set to true (present).
-// If a field is missing, it returns an error.
-func CheckAllFields(m Fields) error {
-	var missing []string
+// CheckAllFieldsResult splits the fields in a map into the present and the missing ones.
+// Both slices are sorted by field name, so reports built from them are deterministic.
+func CheckAllFieldsResult(m Fields) (present []string, missing []string) {
 	for name, ok := range m {
-		if !ok {
+		if ok {
+			present = append(present, name)
+		} else {
 			missing = append(missing, name)
 		}
 	}
+	sort.Strings(present)
+	sort.Strings(missing)
+	return present, missing
+}
+
+// CheckAllFields checks if all fields in a map are set to true (present).
+// If a field is missing, it returns an error listing the missing fields in sorted order.
+func CheckAllFields(m Fields) error {
+	_, missing := CheckAllFieldsResult(m)
 	if len(missing) > 0 {
 		return fmt.Errorf("%w: %s", ErrFieldMissing, strings.Join(missing, ", "))
 	}
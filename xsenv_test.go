@@ -6,7 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"strings"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -56,6 +56,91 @@ func TestLoadEnv(t *testing.T) {
 	assert.Equal(t, FileSource, env.Source)
 }
 
+func TestLoadEnvArray(t *testing.T) {
+	t.Setenv(EnvironmentKey, `[
+		{"name": "uaa", "label": "xsuaa", "credentials": {"clientid": "client"}},
+		{"name": "db", "label": "hana"},
+		{"name": "plain"}
+	]`)
+	env, err := LoadEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, EnvironmentSource, env.Source)
+	assert.Equal(t, []string{"db", "plain", "uaa"}, env.Names())
+
+	configs, err := LoadGroup[map[string]string](env, "xsuaa")
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]string{{"clientid": "client"}}, configs)
+	assert.NoError(t, env.AssertLabel("db", "hana"))
+	_, err = LoadGroup[map[string]string](env, defaultGroup)
+	assert.NoError(t, err)
+
+	// the value of VCAP_SERVICES may be an array as well
+	env, err = loadEnvFromBytes([]byte(`{"VCAP_SERVICES": [{"name": "uaa", "label": "xsuaa"}]}`), RawSource)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"uaa"}, env.Names())
+
+	_, err = loadEnvFromBytes([]byte(`["uaa"]`), RawSource)
+	assert.Error(t, err)
+}
+
+func TestLoadEnvInvalidConfig(t *testing.T) {
+	for _, input := range []string{`"VCAP_SERVICES"`, `42`, ` -1.5`, `true`, `{"VCAP_SERVICES": "{}"}`, `{"VCAP_SERVICES": false}`} {
+		_, err := LoadEnvFromReader(bytes.NewReader([]byte(input)))
+		assert.ErrorIs(t, err, ErrInvalidConfig, input)
+		_, streamErr := LoadEnvFromReaderStreaming(bytes.NewReader([]byte(input)))
+		assert.ErrorIs(t, streamErr, ErrInvalidConfig, input)
+		assert.Equal(t, err, streamErr, input)
+	}
+
+	_, err := LoadEnvFromReader(bytes.NewReader([]byte(`42`)))
+	assert.EqualError(t, err, "invalid configuration: expected an object holding VCAP_SERVICES or an array of services, got number")
+	_, err = LoadEnvFromReader(bytes.NewReader([]byte(`{"VCAP_SERVICES": "{}"}`)))
+	assert.EqualError(t, err, "invalid configuration: expected VCAP_SERVICES to be an object of service groups or an array of services, got string")
+
+	// invalid JSON is reported as such
+	_, err = LoadEnvFromReader(bytes.NewReader([]byte(`VCAP_SERVICES={}`)))
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidConfig)
+}
+
+func TestLoadEnvLenientMetadata(t *testing.T) {
+	for _, service := range []string{
+		`{"name": "a", "label": {"x": 1}}`,
+		`{"name": "a", "binding_name": ["b"]}`,
+		`{"name": "a", "tags": "single"}`,
+		`{"name": "a", "tags": ["a", 1]}`,
+		`{"name": "a", "plan": 1}`,
+		`{"name": "a", "instance_guid": 5}`,
+		`{"name": "a", "instance_name": {"x": 1}}`,
+	} {
+		env, err := LoadEnvFromReader(bytes.NewReader([]byte(`{"VCAP_SERVICES": {"group": [` + service + `]}}`)))
+		assert.NoError(t, err, service)
+		_, ok := env.Raw("a")
+		assert.True(t, ok, service)
+	}
+
+	// fallback names holding another type are ignored
+	env, err := LoadEnvFromReader(bytes.NewReader([]byte(`{"VCAP_SERVICES": {"group": [
+		{"binding_name": 1, "label": "xsuaa"}
+	]}}`)), WithEmptyNames(FallbackEmptyNames))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"xsuaa"}, env.Names())
+
+	env, err = LoadEnvFromReader(bytes.NewReader([]byte(`{"VCAP_SERVICES": {"group": [
+		{"name": "a", "label": "xsuaa", "tags": "single", "plan": 1}
+	]}}`)))
+	assert.NoError(t, err)
+	service, ok := env.Service("a")
+	assert.True(t, ok)
+	assert.Equal(t, "xsuaa", service.Label)
+	assert.Nil(t, service.Tags)
+	assert.Empty(t, service.Plan)
+
+	// the name must still be a string
+	_, err = LoadEnvFromReader(bytes.NewReader([]byte(`{"VCAP_SERVICES": {"group": [{"name": 1}]}}`)))
+	assert.Error(t, err)
+}
+
 func TestLoadEnvFromReader(t *testing.T) {
 	reader := bytes.NewBufferString(`{"VCAP_SERVICES": {"test_service": [{"name": "test"}]}}`)
 	env, err := LoadEnvFromReader(reader)
@@ -126,7 +211,7 @@ func TestCheckAllFields(t *testing.T) {
 				"username": false,
 				"password": false, // both fields are missing
 			},
-			expected: fmt.Errorf("%w: %s", ErrFieldMissing, "username, password"),
+			expected: fmt.Errorf("%w: %s", ErrFieldMissing, "password, username"),
 		},
 		{
 			name:     "Empty fields map",
@@ -142,9 +227,286 @@ func TestCheckAllFields(t *testing.T) {
 				assert.NoError(t, err)
 			} else {
 				assert.True(t, errors.Is(err, ErrFieldMissing))
-				// Since map iteration is random, we need to compare without considering the order
-				assert.ElementsMatch(t, strings.Split(tc.expected.Error(), ": ")[1:], strings.Split(err.Error(), ": ")[1:])
+				assert.EqualError(t, err, tc.expected.Error())
 			}
 		})
 	}
 }
+
+func TestCheckAllFieldsResult(t *testing.T) {
+	present, missing := CheckAllFieldsResult(Fields{
+		"username": true,
+		"password": false,
+		"url":      true,
+		"clientid": false,
+		"host":     true,
+	})
+	assert.Equal(t, []string{"host", "url", "username"}, present)
+	assert.Equal(t, []string{"clientid", "password"}, missing)
+
+	present, missing = CheckAllFieldsResult(Fields{})
+	assert.Empty(t, present)
+	assert.Empty(t, missing)
+}
+
+func TestLoadServices(t *testing.T) {
+	data := `{"VCAP_SERVICES": {"test_service": [{"name": "first"}, {"name": "second"}]}}`
+	env, _ := loadEnvFromBytes([]byte(data), RawSource)
+
+	first := new(MockUnmarshalService)
+	first.On("UnmarshalService", mock.Anything).Return(nil)
+	second := new(MockUnmarshalService)
+	second.On("UnmarshalService", mock.Anything).Return(ErrFieldMissing)
+
+	err := env.LoadServices(map[string]UnmarshalService{
+		"first":   first,
+		"second":  second,
+		"missing": first,
+	})
+	assert.ErrorIs(t, err, ErrServiceNotFound)
+	assert.ErrorIs(t, err, ErrFieldMissing)
+	assert.Contains(t, err.Error(), "missing: "+ErrServiceNotFound.Error())
+	assert.Contains(t, err.Error(), "second: "+ErrFieldMissing.Error())
+	assert.NotContains(t, err.Error(), "first")
+	first.AssertExpectations(t)
+	second.AssertExpectations(t)
+
+	// no failures
+	assert.NoError(t, env.LoadServices(map[string]UnmarshalService{"first": first}))
+
+	// fail fast stops at the first failure in sorted order
+	err = env.LoadServices(map[string]UnmarshalService{
+		"first":   first,
+		"second":  second,
+		"missing": first,
+	}, WithFailFast())
+	assert.EqualError(t, err, "missing: "+ErrServiceNotFound.Error())
+	assert.NotErrorIs(t, err, ErrFieldMissing)
+}
+
+func TestSourceOf(t *testing.T) {
+	env, _ := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"test_service": [{"name": "test"}]}}`), FileSource)
+	source, ok := env.SourceOf("Test")
+	assert.True(t, ok)
+	assert.Equal(t, FileSource, source)
+
+	// environments created by hand fall back to their Source
+	msg := json.RawMessage(`{"name": "manual"}`)
+	manual := &Env{Source: RawSource, ServicesByName: map[string]*json.RawMessage{"manual": &msg}}
+	source, ok = manual.SourceOf("manual")
+	assert.True(t, ok)
+	assert.Equal(t, RawSource, source)
+}
+
+func TestFromServices(t *testing.T) {
+	env, err := FromServices(map[string]any{
+		"portal-uaa": map[string]string{"clientid": "sb-portal"},
+		"db":         map[string]any{"host": "db.example.com", "port": "5432"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, RawSource, env.Source)
+	assert.Len(t, env.ServicesByName, 2)
+	assert.JSONEq(t, `{"name": "db", "credentials": {"host": "db.example.com", "port": "5432"}}`,
+		string(*env.ServicesByName["db"]))
+
+	var config testHANAConfig
+	assert.NoError(t, decodeService(&config, env.ServicesByName["db"]))
+	assert.Equal(t, "db.example.com", config.Host)
+
+	// names must be unique after normalization
+	_, err = FromServices(map[string]any{"DB": nil, "db": nil})
+	assert.ErrorIs(t, err, ErrDuplicateService)
+
+	_, err = FromServices(map[string]any{"invalid": make(chan int)})
+	assert.ErrorContains(t, err, "invalid: ")
+}
+
+func TestOriginalCasing(t *testing.T) {
+	data := `{"VCAP_SERVICES": {"xsuaa": [{"name": "Portal-UAA"}], "hana": [{"name": "portal-DB"}]}}`
+	env, err := loadEnvFromBytes([]byte(data), RawSource)
+	assert.NoError(t, err)
+
+	// names keep their original casing
+	assert.Equal(t, []string{"Portal-UAA", "portal-DB"}, env.Names())
+	assert.Contains(t, env.ServicesByName, "Portal-UAA")
+
+	// lookups stay case-insensitive
+	mockService := new(MockUnmarshalService)
+	mockService.On("UnmarshalService", mock.Anything).Return(nil)
+	assert.NoError(t, env.LoadService(mockService, "portal-uaa"))
+	assert.NoError(t, env.LoadService(mockService, "PORTAL-db"))
+
+	// same-named services replace each other regardless of casing
+	assert.NoError(t, env.Set("PORTAL-UAA", map[string]string{}))
+	assert.Equal(t, []string{"Portal-UAA", "portal-DB"}, env.Names())
+
+	// services added by hand are found as well
+	msg := json.RawMessage(`{"name": "Manual"}`)
+	env.ServicesByName["Manual"] = &msg
+	assert.NoError(t, env.LoadService(mockService, "manual"))
+}
+
+func TestLoadServicePlainPointer(t *testing.T) {
+	data := `{"VCAP_SERVICES": {
+		"hana": [{"name": "db", "credentials": {"host": "db.example.com", "port": "30015"}}],
+		"user-provided": [{"name": "plain", "host": "plain.example.com"}]
+	}}`
+	env, _ := loadEnvFromBytes([]byte(data), RawSource)
+
+	// credentials are decoded into plain structs
+	var config testHANAConfig
+	assert.NoError(t, env.LoadService(&config, "db"))
+	assert.Equal(t, testHANAConfig{Host: "db.example.com", Port: "30015"}, config)
+
+	// the whole service is decoded if it has no credentials
+	var plain testHANAConfig
+	assert.NoError(t, env.LoadService(&plain, "plain"))
+	assert.Equal(t, "plain.example.com", plain.Host)
+
+	// UnmarshalService still takes precedence
+	var named testNamedService
+	assert.NoError(t, env.LoadService(&named, "db"))
+	assert.Equal(t, "db", named.Name)
+
+	var m map[string]string
+	assert.NoError(t, env.LoadService(&m, "db"))
+	assert.Equal(t, "db.example.com", m["host"])
+
+	assert.Error(t, env.LoadService(config, "db"))
+	assert.ErrorIs(t, env.LoadService(&config, "nonexistent"), ErrServiceNotFound)
+}
+
+func TestRaw(t *testing.T) {
+	env, _ := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"test_service": [{"name": "Test"}]}}`), RawSource)
+	raw, ok := env.Raw("test")
+	assert.True(t, ok)
+	assert.JSONEq(t, `{"name": "Test"}`, string(raw))
+
+	_, ok = env.Raw("nonexistent")
+	assert.False(t, ok)
+}
+
+func TestLoadServicesMap(t *testing.T) {
+	fragment := `{"xsuaa": [{"name": "uaa"}], "hana": [{"name": "db"}, {"label": "hana"}]}`
+	env, err := LoadServicesMap([]byte(fragment))
+	assert.NoError(t, err)
+	assert.Equal(t, RawSource, env.Source)
+	assert.Equal(t, []string{"db", "uaa"}, env.Names())
+
+	wrapped, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": `+fragment+`}`), RawSource)
+	assert.NoError(t, err)
+	assert.Equal(t, wrapped.ServicesByName, env.ServicesByName)
+
+	// options apply just like they do for complete configurations
+	env, err = LoadServicesMap([]byte(fragment), WithEmptyNames(FallbackEmptyNames))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db", "hana", "uaa"}, env.Names())
+
+	_, err = LoadServicesMap([]byte(`{"VCAP_SERVICES": {"xsuaa": []}}`))
+	assert.Error(t, err)
+}
+
+func TestLoadEnvFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.json")
+	override := filepath.Join(dir, "override.json")
+	missing := filepath.Join(dir, "missing.json")
+	assert.NoError(t, os.WriteFile(base, []byte(`{"VCAP_SERVICES": {
+		"xsuaa": [{"name": "uaa", "credentials": {"clientid": "base"}}],
+		"hana": [{"name": "db"}]
+	}}`), 0o600))
+	assert.NoError(t, os.WriteFile(override, []byte(`{"VCAP_SERVICES": {
+		"xsuaa": [{"name": "UAA", "credentials": {"clientid": "override"}}]
+	}}`), 0o600))
+
+	env, err := LoadEnvFromFiles([]string{base, override})
+	assert.NoError(t, err)
+	assert.Equal(t, FileSource, env.Source)
+	assert.Equal(t, []string{"UAA", "db"}, env.Names())
+	clientID, err := GetString(env.ServicesByName["UAA"], "credentials.clientid")
+	assert.NoError(t, err)
+	assert.Equal(t, "override", clientID)
+
+	_, err = LoadEnvFromFiles([]string{base, missing, override})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	env, err = LoadEnvFromFiles([]string{base, missing, override}, WithOptionalFiles())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"UAA", "db"}, env.Names())
+
+	// requirements are checked on the merged result
+	_, err = LoadEnvFromFiles([]string{override}, WithMinServices(2))
+	assert.ErrorIs(t, err, ErrTooFewServices)
+	_, err = LoadEnvFromFiles([]string{base, override}, WithMinServices(2))
+	assert.NoError(t, err)
+}
+
+func FuzzLoadEnvFromReader(f *testing.F) {
+	f.Add([]byte(`{"VCAP_SERVICES": {"xsuaa": [{"name": "uaa", "label": "xsuaa", "tags": ["a"], "credentials": {}}]}}`))
+	f.Add([]byte(`{"VCAP_SERVICES": {"a": [null, {"name": ""}, {"binding_name": "b"}]}}`))
+	f.Add([]byte(`{"VCAP_SERVICES": {"a": null}}`))
+	f.Add([]byte(`{"vcap_services": {"a": [{"name": 1}]}}`))
+	f.Add([]byte(`[]`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, policy := range []EmptyNamePolicy{SkipEmptyNames, RejectEmptyNames, FallbackEmptyNames} {
+			env, err := LoadEnvFromReader(bytes.NewReader(data), WithEmptyNames(policy), WithMaxDepth(64))
+			if err == nil {
+				_ = env.Names()
+				_, _ = env.Marshal()
+			}
+			_, _ = LoadEnvFromReaderStreaming(bytes.NewReader(data), WithEmptyNames(policy))
+		}
+	})
+}
+
+func TestRangeGroup(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {
+		"rabbitmq": [{"name": "orders"}, {"name": "Events"}, {"name": "audit"}],
+		"xsuaa": [{"name": "uaa"}]
+	}}`), RawSource)
+	assert.NoError(t, err)
+
+	var names []string
+	env.RangeGroup("rabbitmq", func(name string, msg *json.RawMessage) bool {
+		assert.Same(t, env.ServicesByName[name], msg)
+		names = append(names, name)
+		return true
+	})
+	assert.Equal(t, []string{"orders", "Events", "audit"}, names)
+
+	names = nil
+	env.RangeGroup("rabbitmq", func(name string, _ *json.RawMessage) bool {
+		names = append(names, name)
+		return name != "Events"
+	})
+	assert.Equal(t, []string{"orders", "Events"}, names)
+
+	env.RangeGroup("nonexistent", func(string, *json.RawMessage) bool {
+		t.Fatal("unexpected call")
+		return false
+	})
+}
+
+func TestLen(t *testing.T) {
+	env, err := loadEnvFromBytes([]byte(`{"VCAP_SERVICES": {"a": [{"name": "one"}, {"name": "two"}]}}`), RawSource)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, env.Len())
+	assert.False(t, env.IsEmpty())
+
+	env.Remove("one")
+	env.Remove("two")
+	assert.Equal(t, 0, env.Len())
+	assert.True(t, env.IsEmpty())
+	assert.True(t, (&Env{}).IsEmpty())
+}
+
+func TestSourceValid(t *testing.T) {
+	for _, source := range []Source{FileSource, EnvironmentSource, RawSource, MergedSource, KubernetesSource, NoneSource} {
+		assert.True(t, source.Valid(), source)
+		assert.Equal(t, string(source), source.String())
+	}
+	for _, source := range []Source{"", "vault", "File", " file"} {
+		assert.False(t, source.Valid(), source)
+	}
+	assert.Equal(t, "environment", fmt.Sprint(EnvironmentSource))
+}
@@ -0,0 +1,40 @@
+// Package xsenvtest provides helpers for writing tests against service bindings.
+package xsenvtest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/darmiel/go-xsenv"
+)
+
+// SetVCAP sets the environment variable read by xsenv.LoadEnv to vcap for the duration of the test.
+// The previous value is restored automatically when the test finishes.
+func SetVCAP(t testing.TB, vcap string) {
+	t.Helper()
+	t.Setenv(xsenv.EnvironmentKey, vcap)
+}
+
+// MustBuildEnv builds an environment from Go values using xsenv.FromServices,
+// mapping service names to their credentials. It fails the test if building fails.
+func MustBuildEnv(t testing.TB, services map[string]any, opts ...xsenv.Option) *xsenv.Env {
+	t.Helper()
+	env, err := xsenv.FromServices(services, opts...)
+	if err != nil {
+		t.Fatalf("building env: %v", err)
+		return nil
+	}
+	return env
+}
+
+// RequireService returns the raw configuration of the service with the given name.
+// It fails the test if the service does not exist.
+func RequireService(t testing.TB, env *xsenv.Env, name string) json.RawMessage {
+	t.Helper()
+	raw, ok := env.Raw(name)
+	if !ok {
+		t.Fatalf("service %q not found, available services: %v", name, env.Names())
+		return nil
+	}
+	return raw
+}
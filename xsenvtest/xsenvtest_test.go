@@ -0,0 +1,53 @@
+package xsenvtest
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/darmiel/go-xsenv"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingTB records fatal errors instead of stopping the test.
+type recordingTB struct {
+	testing.TB
+	fatal string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Fatalf(format string, args ...any) {
+	r.fatal = fmt.Sprintf(format, args...)
+}
+
+func TestSetVCAP(t *testing.T) {
+	t.Run("set", func(t *testing.T) {
+		SetVCAP(t, `{"VCAP_SERVICES": {"xsuaa": [{"name": "uaa"}]}}`)
+		env, err := xsenv.LoadEnv()
+		assert.NoError(t, err)
+		assert.Equal(t, xsenv.EnvironmentSource, env.Source)
+		RequireService(t, env, "uaa")
+	})
+	_, ok := os.LookupEnv(xsenv.EnvironmentKey)
+	assert.False(t, ok)
+}
+
+func TestMustBuildEnv(t *testing.T) {
+	env := MustBuildEnv(t, map[string]any{
+		"uaa": map[string]string{"clientid": "sb-portal"},
+	})
+	assert.JSONEq(t, `{"name": "uaa", "credentials": {"clientid": "sb-portal"}}`, string(RequireService(t, env, "UAA")))
+
+	rec := &recordingTB{TB: t}
+	assert.Nil(t, MustBuildEnv(rec, map[string]any{"a": nil, "A": nil}))
+	assert.Contains(t, rec.fatal, "building env")
+}
+
+func TestRequireService(t *testing.T) {
+	env := MustBuildEnv(t, map[string]any{"uaa": nil})
+
+	rec := &recordingTB{TB: t}
+	assert.Nil(t, RequireService(rec, env, "db"))
+	assert.Equal(t, `service "db" not found, available services: [uaa]`, rec.fatal)
+}
@@ -0,0 +1,198 @@
+// Package xsuaa provides the credentials of XSUAA service bindings
+// together with accessors for the commonly used OAuth endpoints.
+package xsuaa
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/darmiel/go-xsenv"
+)
+
+// Label is the label of XSUAA services.
+const Label = "xsuaa"
+
+var (
+	// ErrInvalidURL indicates that the base URL of the credentials cannot be used to build endpoints.
+	ErrInvalidURL = errors.New("invalid base url")
+	// ErrInvalidVerificationKey indicates that the verification key of the credentials cannot be parsed.
+	ErrInvalidVerificationKey = errors.New("invalid verification key")
+)
+
+// Credentials holds the credentials of an XSUAA service binding.
+// It implements xsenv.UnmarshalService.
+type Credentials struct {
+	ClientID        string `json:"clientid"`
+	ClientSecret    string `json:"clientsecret" xsenv:"secret"`
+	XSAppName       string `json:"xsappname"`
+	URL             string `json:"url"`
+	UAADomain       string `json:"uaadomain"`
+	IdentityZone    string `json:"identityzone"`
+	TenantID        string `json:"tenantid"`
+	VerificationKey string `json:"verificationkey"`
+
+	// CredentialType is the kind of client credentials, e.g. "binding-secret" or "x509".
+	CredentialType string `json:"credential-type"`
+	// CertURL is the base URL used for certificate based authentication.
+	CertURL string `json:"certurl"`
+	// Cert and PrivateKey hold the client certificate and key of x509 credentials as delivered
+	// by the binding, use Certificate and Key to obtain the decoded PEM bytes.
+	Cert       string `json:"certificate"`
+	PrivateKey string `json:"key" xsenv:"secret"`
+}
+
+// UnmarshalService unmarshals the credentials of an XSUAA service.
+// The client id and either the url or the identity zone and uaa domain are required.
+func (c *Credentials) UnmarshalService(message *json.RawMessage) error {
+	parsed := struct {
+		Credentials Credentials `json:"credentials"`
+	}{}
+	if err := json.Unmarshal(*message, &parsed); err != nil {
+		return err
+	}
+	if err := xsenv.CheckAllFields(xsenv.Fields{
+		"clientid": parsed.Credentials.ClientID != "",
+		"url":      parsed.Credentials.baseURL() != "",
+	}); err != nil {
+		return err
+	}
+	*c = parsed.Credentials
+	return nil
+}
+
+// baseURL returns the url of the credentials or, if absent, the url derived from the identity zone and uaa domain.
+func (c Credentials) baseURL() string {
+	if c.URL != "" {
+		return c.URL
+	}
+	if c.IdentityZone != "" && c.UAADomain != "" {
+		return "https://" + c.IdentityZone + "." + c.UAADomain
+	}
+	return ""
+}
+
+// Endpoint returns the absolute URL of path relative to the base URL of the credentials.
+// It returns ErrInvalidURL if the base URL is missing or not an absolute URL.
+func (c Credentials) Endpoint(path string) (*url.URL, error) {
+	base := c.baseURL()
+	if base == "" {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidURL, xsenv.MissingFieldError("url"))
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("%w: %q is not absolute", ErrInvalidURL, base)
+	}
+	return u.JoinPath(strings.TrimPrefix(path, "/")), nil
+}
+
+// endpoint returns the endpoint for path as a string, or an empty string if the base URL is invalid.
+func (c Credentials) endpoint(path string) string {
+	u, err := c.Endpoint(path)
+	if err != nil {
+		return ""
+	}
+	return u.String()
+}
+
+// TokenURL returns the URL of the OAuth token endpoint (/oauth/token).
+// It returns an empty string if the base URL is invalid, use Endpoint to get the error.
+func (c Credentials) TokenURL() string {
+	return c.endpoint("/oauth/token")
+}
+
+// AuthorizeURL returns the URL of the OAuth authorization endpoint (/oauth/authorize).
+// It returns an empty string if the base URL is invalid, use Endpoint to get the error.
+func (c Credentials) AuthorizeURL() string {
+	return c.endpoint("/oauth/authorize")
+}
+
+// TokenKeysURL returns the URL of the endpoint serving the token signing keys (/token_keys).
+// It returns an empty string if the base URL is invalid, use Endpoint to get the error.
+func (c Credentials) TokenKeysURL() string {
+	return c.endpoint("/token_keys")
+}
+
+// Certificate returns the PEM encoded client certificate of x509 credentials.
+// Escaped newlines and base64 encoded certificates are decoded, see xsenv.DecodeCert.
+func (c Credentials) Certificate() ([]byte, error) {
+	if c.Cert == "" {
+		return nil, xsenv.MissingFieldError("certificate")
+	}
+	return xsenv.DecodeCert(c.Cert)
+}
+
+// Key returns the PEM encoded private key of x509 credentials.
+// Escaped newlines and base64 encoded keys are decoded, see xsenv.DecodeCert.
+func (c Credentials) Key() ([]byte, error) {
+	if c.PrivateKey == "" {
+		return nil, xsenv.MissingFieldError("key")
+	}
+	return xsenv.DecodeCert(c.PrivateKey)
+}
+
+// PublicKey returns the public key of the verification key, used to verify the signatures of tokens
+// issued by the service, e.g. an *rsa.PublicKey. The key is accepted as PEM, also with escaped newlines ("\n")
+// or without any line breaks, as well as PKIX or PKCS #1 encoded.
+// It returns ErrFieldMissing if the credentials have no verification key and ErrInvalidVerificationKey
+// if it cannot be parsed.
+func (c Credentials) PublicKey() (crypto.PublicKey, error) {
+	if c.VerificationKey == "" {
+		return nil, xsenv.MissingFieldError("verificationkey")
+	}
+	der, err := verificationKeyDER(c.VerificationKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidVerificationKey, err)
+	}
+	if key, err := x509.ParsePKIXPublicKey(der); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS1PublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidVerificationKey, err)
+	}
+	return key, nil
+}
+
+// verificationKeyDER returns the DER bytes of the PEM encoded key s, tolerating escaped or missing line breaks.
+func verificationKeyDER(s string) ([]byte, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), `\n`, "\n")
+	if block, _ := pem.Decode([]byte(s)); block != nil {
+		return block.Bytes, nil
+	}
+	// the key is on a single line, drop the armor and decode the base64 body
+	if _, body, ok := strings.Cut(s, "-----BEGIN "); ok {
+		if _, s, ok = strings.Cut(body, "-----"); !ok {
+			return nil, errors.New("malformed pem header")
+		}
+		s, _, _ = strings.Cut(s, "-----END ")
+	}
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+}
+
+// redacted returns a copy of c with its secrets replaced by xsenv.RedactedValue.
+func (c Credentials) redacted() Credentials {
+	return xsenv.Redacted(c).(Credentials)
+}
+
+// String returns a representation of the credentials with secrets redacted, safe for logging.
+func (c Credentials) String() string {
+	// plain has no methods, so formatting it does not recurse into String
+	type plain Credentials
+	return fmt.Sprintf("%+v", plain(c.redacted()))
+}
+
+// GoString returns a Go syntax representation of the credentials with secrets redacted, safe for logging.
+func (c Credentials) GoString() string {
+	type plain Credentials
+	return strings.Replace(fmt.Sprintf("%#v", plain(c.redacted())), "xsuaa.plain", "xsuaa.Credentials", 1)
+}